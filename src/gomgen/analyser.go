@@ -1,5 +1,7 @@
 package gomgen
 
+// source of the tables. Analyze fills gen.Tables with the tables,
+// their columns, foreign keys as one-to-one relations and indexes
 type Analyzer interface {
-	Analyze(gen *Generator)
+	Analyze(gen *Generator) error
 }
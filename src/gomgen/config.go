@@ -0,0 +1,179 @@
+package gomgen
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// Generator configuration. Column lists match either a column
+// name in any table or a specific table.column
+type Config struct {
+	// integer columns used for optimistic locking
+	VersionColumns []string
+
+	// nullable datetime columns marking soft deleted rows
+	SoftDeleteColumns []string
+
+	// datetime columns set on insert
+	CreatedColumns []string
+
+	// datetime columns set on insert and update
+	UpdatedColumns []string
+
+	// import path of the runtime package gomgen/orm used by the
+	// generated code
+	Runtime string
+
+	// directory with templates overriding the defaults of the same
+	// file name. See templates/README.md
+	TemplateDir string
+
+	// write the code of each table into <table>_gen.go, rewritten on
+	// every run, and create <table>.go for the hand written code once.
+	// model.go keeps the shared code
+	SplitFiles bool
+
+	// additional outputs rendered from user templates
+	Extra []ExtraTemplate
+
+	// struct tags of the entity fields
+	Tags TagConfig
+
+	// custom Go types of the columns. The first matching mapping is used
+	Types []TypeMapping
+
+	// type of the nullable fields. "sql" for sql.NullInt64 and
+	// friends or "pointer" for *int64, *string, *time.Time, ...
+	NullStyle string
+
+	// null style of individual columns by column or table.column
+	// overriding NullStyle
+	ColumnNullStyle map[string]string
+
+	// generate MarshalJSON and UnmarshalJSON encoding null values
+	// as null and times with JsonTimeLayout
+	JsonMethods    bool
+	JsonTimeLayout string
+}
+
+// struct tag generation. Tags come in the order db, json and
+// then the custom tags sorted by name
+type TagConfig struct {
+	// add db:"<column>"
+	Db bool
+
+	// json key naming. "snake", "camel" or empty for no json tags
+	Json string
+
+	// add omitempty to the json tags of nullable columns. Otherwise
+	// null values are encoded as null
+	OmitEmpty bool
+
+	// additional tags. Tag name to a template of the value executed
+	// with the *Field. For example "xml": "{{ .RealName }}"
+	Custom map[string]string
+
+	// per column tag values by table.column and tag name. Empty
+	// value removes the tag from the column
+	Override map[string]map[string]string
+}
+
+// user template rendered into Generator.Files
+type ExtraTemplate struct {
+	// path of the template file
+	Template string
+
+	// file name template executed with the same data as the template,
+	// for example "{{ snake .EntitySingular }}_repository.go". Go files
+	// are formatted with gofmt
+	Output string
+
+	// "schema" renders once with the *Generator, "table" once per
+	// *Table and "relation" once per relation with the *RelationData
+	Scope string
+}
+
+// Go type of the columns matching SqlType and Column. Empty
+// criteria match any column
+type TypeMapping struct {
+	// sql type without the size like "decimal" or the full
+	// column type like "binary(16)"
+	SqlType string
+
+	// column name pattern like "*_uuid" or "table.column". Patterns
+	// are matched with path.Match
+	Column string
+
+	// the Go type and the package providing it
+	GoType string
+	Import string
+
+	// type implements sql.Scanner and driver.Valuer. Otherwise it is
+	// converted from and to the built-in type of the column, which
+	// works for not null numbers, bools and strings only
+	Scanner bool
+}
+
+// check if the mapping applies to the table field
+func (this *TypeMapping) match(table *Table, field *Field) bool {
+	if this.SqlType != "" && !strings.EqualFold(this.SqlType, field.BaseType) && !strings.EqualFold(this.SqlType, field.SqlType) {
+		return false
+	}
+	if this.Column != "" {
+		name := field.RealName
+		if strings.Contains(this.Column, ".") {
+			name = table.Name + "." + name
+		}
+		if ok, _ := path.Match(this.Column, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// create config with the default conventions
+func NewConfig() *Config {
+	return &Config{
+		VersionColumns:    []string{"version", "lock_version"},
+		SoftDeleteColumns: []string{"deleted_at"},
+		CreatedColumns:    []string{"created_at", "create_date"},
+		UpdatedColumns:    []string{"updated_at", "update_date"},
+		Tags: TagConfig{
+			Db:   true,
+			Json: "snake",
+		},
+		Runtime:        "gomgen/orm",
+		NullStyle:      "sql",
+		JsonMethods:    true,
+		JsonTimeLayout: time.RFC3339,
+	}
+}
+
+// check if nullable field of the table is a pointer
+func (this *Config) nullPointer(table *Table, field *Field) bool {
+	style := this.NullStyle
+	if value, ok := this.ColumnNullStyle[field.RealName]; ok {
+		style = value
+	}
+	if value, ok := this.ColumnNullStyle[table.Name+"."+field.RealName]; ok {
+		style = value
+	}
+	return style == "pointer"
+}
+
+// find the first table field matching the column list
+func (this *Config) match(table *Table, columns []string) *Field {
+	for _, column := range columns {
+		if parts := strings.SplitN(column, ".", 2); len(parts) == 2 {
+			if parts[0] != table.Name {
+				continue
+			}
+			column = parts[1]
+		}
+		if field := table.GetField(column); field != nil {
+			return field
+		}
+	}
+	return nil
+}
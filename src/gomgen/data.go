@@ -0,0 +1,166 @@
+package gomgen
+
+// Data passed to the templates. Besides these the header.tpl gets
+// the *Generator and struct.tpl the *Table. Expressions are Go code
+// ready to be placed in the generated source
+
+// scan.tpl
+type ScanData struct {
+	*Table
+	Vars   map[string]string // declared extra variables by type
+	Params string            // params for the Scan method
+	Inits  []string          // value loads for the variables
+}
+
+// meta.tpl
+type MetaData struct {
+	*Table
+	Columns      string // quoted escaped names of all columns
+	Identity     string // quoted escaped names of the primary key columns
+	Inserted     string // quoted escaped names of the inserted columns
+	Values       string // values of the inserted columns
+	AutoIncField *Field
+	IdValue      string // int64 id converted to the AutoIncField type
+}
+
+// find.tpl
+type FindData struct {
+	*Table
+	IdentityField *Field // single integer primary key
+	Select        string // expression of the select statement
+}
+
+// validate.tpl
+type ValidateData struct {
+	*Table
+	Checks []Check
+}
+
+// single constraint check of the Validate method
+type Check struct {
+	Field   *Field
+	Cond    string // true when the value is invalid
+	Message string // quoted error message
+}
+
+// save.tpl
+type SaveData struct {
+	*Table
+	UpsertCols   string
+	UpsertVals   string
+	UpsertParams string
+	UpsertSet    string // assignments of the ON DUPLICATE KEY UPDATE
+	Changes      []Change
+	Where        string // condition matching the row by identity
+	WhereParams  string
+	VersionField *Field
+	AutoIncField *Field
+	IdValue      string // lastId converted to the AutoIncField type
+	HasUpdate    bool   // Update and Upsert are generated
+	HasSave      bool   // Save is generated
+	SetCreated   string // statement setting the creation time
+	SetUpdated   string // statement setting the update time
+}
+
+// update of a changed column
+type Change struct {
+	Cond  string // true when the field differs from the snapshot
+	Col   string // escaped column name
+	Param string // value bound to the column
+}
+
+// delete.tpl
+type DeleteData struct {
+	*Table
+	Where       string // condition matching the row by identity
+	WhereParams string
+}
+
+// bulk_insert.tpl
+type BulkInsertData struct {
+	*Table
+	Cols         string // escaped inserted columns
+	Row          string // placeholders of a single row
+	Params       string // values of a single row
+	AutoIncField *Field
+	IdValue      string // id of the row converted to the AutoIncField type
+	Touch        bool   // entity has managed timestamps
+}
+
+// json.tpl
+type JsonData struct {
+	*Table
+	Name   string // name of the json shadow struct
+	Fields []JsonField
+}
+
+// field of the json shadow struct
+type JsonField struct {
+	*Field
+	Type       string // type in the json struct
+	DecodeType string // type of the value decoded into a jsonValue
+	Marshal    string // statement setting the json struct field
+	Unmarshal  string // statement setting the entity field
+}
+
+// schema.tpl
+type SchemaData struct {
+	Fingerprint string // sha256 of the tables and columns
+	Tables      []SchemaTable
+	DDL         []string // statements creating the tables of the models
+}
+
+// table the models were generated from
+type SchemaTable struct {
+	Name    string
+	Columns []SchemaColumn
+}
+
+// column the models were generated from
+type SchemaColumn struct {
+	Name     string
+	Type     string // column type without the integer display width
+	Nullable bool
+}
+
+// dot.tpl and mermaid.tpl
+type DiagramData struct {
+	*Generator
+	Entities []DiagramEntity
+	Edges    []DiagramEdge
+}
+
+// table of the diagram
+type DiagramEntity struct {
+	*Table
+	Columns []DiagramColumn
+}
+
+// column of the diagram entity
+type DiagramColumn struct {
+	*Field
+	Foreign bool // column of a foreign key
+	Unique  bool // column has a unique index of its own
+}
+
+// foreign key from the Table of the relation to the TargetEntity
+type DiagramEdge struct {
+	*Relation
+	Optional bool // foreign key can be null
+	Single   bool // foreign key is unique, at most one row points to the target
+}
+
+// one_to_one.tpl, one_to_many.tpl, many_to_many.tpl, cached_relation.tpl
+// and load_relation.tpl. Key expressions come with their validity
+// conditions, which are empty for not null columns
+type RelationData struct {
+	*Relation
+	Single                bool   // at most one related entity
+	Through               bool   // related through the connecting table
+	Key, KeyValid         string // entity side column
+	ItemKey, ItemValid    string // target column
+	LinkSrc, LinkSrcValid string // connecting table column pointing to the entity
+	LinkDst, LinkDstValid string // connecting table column pointing to the target
+	SetKey                string // foreign key update done by the setter
+	Nullable              bool   // foreign key can be null
+}
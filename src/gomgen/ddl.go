@@ -0,0 +1,27 @@
+package gomgen
+
+// database flavour of the DDL emitted for the tables
+type Dialect interface {
+	// CREATE TABLE statement without the foreign keys
+	CreateTable(table *Table) string
+
+	// statement adding the foreign key of the one-to-one relation
+	AddForeignKey(rel *Relation) string
+}
+
+// statements creating the tables. The foreign keys are added after
+// all the tables exist, so the order of the tables does not matter
+func (this *Generator) DDL() []string {
+	var stmts []string
+	for _, table := range this.Tables {
+		stmts = append(stmts, this.Dialect.CreateTable(table))
+	}
+	for _, table := range this.Tables {
+		for _, rel := range table.Relations {
+			if rel.Type == OneToOne {
+				stmts = append(stmts, this.Dialect.AddForeignKey(rel))
+			}
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,98 @@
+package gomgen
+
+import (
+	"bytes"
+	"strings"
+)
+
+// render the entity relationship diagram of the tables as Graphviz DOT
+func (this *Generator) Dot() ([]byte, error) {
+	return this.genDiagram("dot.tpl")
+}
+
+// render the entity relationship diagram of the tables as Mermaid erDiagram
+func (this *Generator) Mermaid() ([]byte, error) {
+	return this.genDiagram("mermaid.tpl")
+}
+
+// render the diagram template
+func (this *Generator) genDiagram(name string) ([]byte, error) {
+	if err := this.loadTemplates(); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := this.render(&out, name, this.diagramData()); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// collect the entities and the foreign key edges
+func (this *Generator) diagramData() DiagramData {
+	data := DiagramData{Generator: this}
+	for _, table := range this.Tables {
+		// single column unique indexes
+		unique := map[*Field]bool{}
+		for _, index := range table.Indexes {
+			if index.Unique && len(index.Fields) == 1 {
+				unique[index.Fields[0]] = true
+			}
+		}
+
+		// foreign keys
+		foreign := map[*Field]bool{}
+		for _, rel := range table.Relations {
+			if rel.Type != OneToOne {
+				continue
+			}
+			foreign[rel.Column] = true
+			data.Edges = append(data.Edges, DiagramEdge{
+				Relation: rel,
+				Optional: rel.Column.Nullable,
+				Single:   unique[rel.Column],
+			})
+		}
+
+		entity := DiagramEntity{Table: table}
+		for _, field := range table.Fields {
+			entity.Columns = append(entity.Columns, DiagramColumn{
+				Field:   field,
+				Foreign: foreign[field],
+				Unique:  unique[field] && !field.Primary,
+			})
+		}
+		data.Entities = append(data.Entities, entity)
+	}
+	return data
+}
+
+// keys of the column, like PK, FK
+func (this DiagramColumn) Keys() string {
+	var keys []string
+	if this.Primary {
+		keys = append(keys, "PK")
+	}
+	if this.Foreign {
+		keys = append(keys, "FK")
+	}
+	if this.Unique {
+		keys = append(keys, "UK")
+	}
+	return strings.Join(keys, ",")
+}
+
+// Go type in the form Mermaid accepts. Mermaid types can not start
+// with a symbol nor contain dots, so the pointers are shown as nullable,
+// the package is dropped and the slice brackets go to the end
+func (this DiagramColumn) MermaidType() string {
+	t := strings.TrimPrefix(this.GoType, "*")
+	slice := strings.HasPrefix(t, "[]")
+	t = strings.TrimPrefix(t, "[]")
+	if i := strings.LastIndex(t, "."); i >= 0 {
+		t = t[i+1:]
+	}
+	if slice {
+		t += "[]"
+	}
+	return t
+}
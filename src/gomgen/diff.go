@@ -0,0 +1,240 @@
+package gomgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lines of unchanged context around the changes
+const diffContext = 3
+
+// line of the edit script
+type diffLine struct {
+	op   byte   // ' ' kept, '-' removed, '+' added
+	text string // line with the line break
+	a, b int    // index of the line in the old and the new text
+}
+
+// unified diff of the old and the new text of the file. Empty when
+// they are the same
+func unifiedDiff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+	ops := diffLines(splitLines(old), splitLines(new))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", name, name)
+	for i := 0; i < len(ops); {
+		if ops[i].op == ' ' {
+			i++
+			continue
+		}
+		// changes closer than twice the context share the hunk
+		end := i
+		for j := i; j < len(ops) && j-end <= 2*diffContext; j++ {
+			if ops[j].op != ' ' {
+				end = j
+			}
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		stop := end + diffContext + 1
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		// hunk header
+		var aCount, bCount int
+		for _, op := range ops[start:stop] {
+			if op.op != '+' {
+				aCount++
+			}
+			if op.op != '-' {
+				bCount++
+			}
+		}
+		aStart, bStart := ops[start].a+1, ops[start].b+1
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+
+		for _, op := range ops[start:stop] {
+			out.WriteByte(op.op)
+			out.WriteString(op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+// split text into lines keeping the line breaks
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edit script turning a into b, based on the longest common subsequence.
+// The common prefix and suffix are skipped, the rest is split in halves
+// (Hirschberg) so memory stays linear in the size of the texts
+func diffLines(a, b []string) []diffLine {
+	// compare the lines as numbers
+	ids := map[string]int{}
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	d := &lineDiff{a: a, b: b, x: intern(a), y: intern(b)}
+	d.diff(0, len(a), 0, len(b))
+	return groupChanges(d.ops)
+}
+
+// state of diffLines
+type lineDiff struct {
+	a, b []string // lines
+	x, y []int    // line ids
+	ops  []diffLine
+}
+
+// append the edit script of a[a0:a1] and b[b0:b1]
+func (this *lineDiff) diff(a0, a1, b0, b1 int) {
+	// common prefix and suffix
+	for a0 < a1 && b0 < b1 && this.x[a0] == this.y[b0] {
+		this.ops = append(this.ops, diffLine{' ', this.a[a0], a0, b0})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a0 < a1 && b0 < b1 && this.x[a1-1] == this.y[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	switch {
+	case a0 == a1:
+		for j := b0; j < b1; j++ {
+			this.ops = append(this.ops, diffLine{'+', this.b[j], a0, j})
+		}
+	case b0 == b1:
+		for i := a0; i < a1; i++ {
+			this.ops = append(this.ops, diffLine{'-', this.a[i], i, b0})
+		}
+	case a1-a0 == 1:
+		// single line is kept when b has it
+		k := b0
+		for k < b1 && this.y[k] != this.x[a0] {
+			k++
+		}
+		if k == b1 {
+			this.ops = append(this.ops, diffLine{'-', this.a[a0], a0, b0})
+			a0++
+		}
+		for j := b0; j < b1; j++ {
+			if j == k {
+				this.ops = append(this.ops, diffLine{' ', this.a[a0], a0, j})
+				a0++
+			} else {
+				this.ops = append(this.ops, diffLine{'+', this.b[j], a0, j})
+			}
+		}
+	default:
+		// split b where the halves of a have the longest common
+		// subsequences together
+		mid := (a0 + a1) / 2
+		head := lcsLengths(this.x[a0:mid], this.y[b0:b1], false)
+		tail := lcsLengths(this.x[mid:a1], this.y[b0:b1], true)
+		k := 0
+		for j := range head {
+			if head[j]+tail[len(tail)-1-j] > head[k]+tail[len(tail)-1-k] {
+				k = j
+			}
+		}
+		this.diff(a0, mid, b0, b0+k)
+		this.diff(mid, a1, b0+k, b1)
+	}
+
+	for i := 0; i < suffix; i++ {
+		this.ops = append(this.ops, diffLine{' ', this.a[a1+i], a1 + i, b1 + i})
+	}
+}
+
+// lengths of the longest common subsequences of a and the prefixes of
+// b, or of the suffixes of a and b when reverse is set. The result at
+// index j is for the first (last) j lines of b
+func lcsLengths(a, b []int, reverse bool) []int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		ai := a[i]
+		if reverse {
+			ai = a[len(a)-1-i]
+		}
+		for j := range b {
+			bj := b[j]
+			if reverse {
+				bj = b[len(b)-1-j]
+			}
+			if ai == bj {
+				cur[j+1] = prev[j] + 1
+			} else if prev[j+1] >= cur[j] {
+				cur[j+1] = prev[j+1]
+			} else {
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// order the removed lines before the added ones in each block of changes
+func groupChanges(ops []diffLine) []diffLine {
+	out := make([]diffLine, 0, len(ops))
+	for i := 0; i < len(ops); {
+		if ops[i].op == ' ' {
+			out = append(out, ops[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(ops) && ops[end].op != ' ' {
+			end++
+		}
+		a, b := ops[i].a, ops[i].b
+		for _, op := range ops[i:end] {
+			if op.op == '-' {
+				out = append(out, diffLine{'-', op.text, a, b})
+				a++
+			}
+		}
+		for _, op := range ops[i:end] {
+			if op.op == '+' {
+				out = append(out, diffLine{'+', op.text, a, b})
+				b++
+			}
+		}
+		i = end
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package gomgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered lines l<from>..l<to>
+func numberedLines(from, to int) string {
+	var out strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&out, "l%d\n", i)
+	}
+	return out.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := numberedLines(1, 20)
+	tests := []struct {
+		name     string
+		old, new string
+		diff     string
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			diff: "",
+		},
+		{
+			name: "missing file",
+			old:  "",
+			new:  "a\nb\n",
+			diff: "--- a/f.go\n+++ b/f.go\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "leftover file",
+			old:  "a\n",
+			new:  "",
+			diff: "--- a/f.go\n+++ b/f.go\n@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "change at the end without line break",
+			old:  "a\nb\nc",
+			new:  "a\nb\nd",
+			diff: "--- a/f.go\n+++ b/f.go\n@@ -1,3 +1,3 @@\n a\n b\n-c\n\\ No newline at end of file\n+d\n\\ No newline at end of file\n",
+		},
+		{
+			name: "line break added at the end",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			diff: "--- a/f.go\n+++ b/f.go\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "changes far apart get own hunks",
+			old:  lines,
+			new:  strings.Replace(strings.Replace(lines, "l2\n", "x2\n", 1), "l18\n", "x18\n", 1),
+			diff: "--- a/f.go\n+++ b/f.go\n" +
+				"@@ -1,5 +1,5 @@\n l1\n-l2\n+x2\n l3\n l4\n l5\n" +
+				"@@ -15,6 +15,6 @@\n l15\n l16\n l17\n-l18\n+x18\n l19\n l20\n",
+		},
+		{
+			name: "changes close together share the hunk",
+			old:  lines,
+			new:  strings.Replace(strings.Replace(lines, "l5\n", "x5\n", 1), "l10\n", "x10\n", 1),
+			diff: "--- a/f.go\n+++ b/f.go\n" +
+				"@@ -2,12 +2,12 @@\n l2\n l3\n l4\n-l5\n+x5\n l6\n l7\n l8\n l9\n-l10\n+x10\n l11\n l12\n l13\n",
+		},
+		{
+			name: "removed lines come before added ones",
+			old:  "a\nb\nc\nd\n",
+			new:  "a\nx\ny\nd\n",
+			diff: "--- a/f.go\n+++ b/f.go\n@@ -1,4 +1,4 @@\n a\n-b\n-c\n+x\n+y\n d\n",
+		},
+		{
+			name: "insertion in the middle",
+			old:  numberedLines(1, 10),
+			new:  numberedLines(1, 5) + "x\n" + numberedLines(6, 10),
+			diff: "--- a/f.go\n+++ b/f.go\n@@ -3,6 +3,7 @@\n l3\n l4\n l5\n+x\n l6\n l7\n l8\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := unifiedDiff("f.go", test.old, test.new); diff != test.diff {
+				t.Errorf("got\n%s\nwant\n%s", diff, test.diff)
+			}
+		})
+	}
+}
+
+func TestDiffLinesIsMinimal(t *testing.T) {
+	tests := []struct {
+		old, new string
+		kept     int
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", 3},
+		{"a\nb\nc\n", "c\nb\na\n", 1},
+		{"a\nb\nc\nd\n", "b\nd\n", 2},
+		{"a\na\nb\na\n", "b\na\na\n", 2},
+		{numberedLines(1, 50), numberedLines(26, 75), 25},
+	}
+	for _, test := range tests {
+		kept := 0
+		var old, new strings.Builder
+		for _, op := range diffLines(splitLines(test.old), splitLines(test.new)) {
+			if op.op == ' ' {
+				kept++
+			}
+			if op.op != '+' {
+				old.WriteString(op.text)
+			}
+			if op.op != '-' {
+				new.WriteString(op.text)
+			}
+		}
+		if kept != test.kept {
+			t.Errorf("%q -> %q: kept %d lines, want %d", test.old, test.new, kept, test.kept)
+		}
+		if old.String() != test.old || new.String() != test.new {
+			t.Errorf("%q -> %q: edit script does not rebuild the texts", test.old, test.new)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package gomgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// parse the Config.Extra templates into the template set. They are
+// named by their path
+func (this *Generator) loadExtras(root *template.Template) error {
+	for _, extra := range this.Config.Extra {
+		src, err := os.ReadFile(extra.Template)
+		if err != nil {
+			return err
+		}
+		if _, err := root.New(extra.Template).Parse(string(src)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render the Config.Extra templates into Files
+func (this *Generator) genExtras() error {
+	for _, extra := range this.Config.Extra {
+		// items to render the template for
+		var items []interface{}
+		switch extra.Scope {
+		case "schema":
+			items = append(items, this)
+		case "table":
+			for _, table := range this.Tables {
+				items = append(items, table)
+			}
+		case "relation":
+			for _, table := range this.Tables {
+				for _, rel := range table.Relations {
+					items = append(items, relationData(rel))
+				}
+			}
+		default:
+			return fmt.Errorf("%s: unknown scope %q", extra.Template, extra.Scope)
+		}
+
+		// output file names
+		output, err := template.New(extra.Template + " output").Funcs(templateFuncs).Parse(extra.Output)
+		if err != nil {
+			return err
+		}
+
+		// render
+		for _, item := range items {
+			var name, code bytes.Buffer
+			if err := output.Execute(&name, item); err != nil {
+				return err
+			}
+			if err := this.render(&code, extra.Template, item); err != nil {
+				return err
+			}
+			file := name.String()
+			if _, ok := this.Files[file]; ok {
+				return fmt.Errorf("%s: output %s is written more than once", extra.Template, file)
+			}
+			src := code.Bytes()
+			if strings.HasSuffix(file, ".go") {
+				if src, err = format.Source(src); err != nil {
+					return fmt.Errorf("%s: %v", file, err)
+				}
+			}
+			this.Files[file] = src
+		}
+	}
+	return nil
+}
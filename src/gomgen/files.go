@@ -0,0 +1,198 @@
+package gomgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// move the table code into <table>_gen.go files with their own imports
+// and add the <table>.go stubs for the hand written code
+func (this *Generator) genSplitFiles(split map[*Table]*bytes.Buffer) error {
+	// shared code keeps only the imports it uses
+	code, err := pruneImports(this.Output.Bytes())
+	if err != nil {
+		return fmt.Errorf("model.go: %v", err)
+	}
+	this.Output.Reset()
+	this.Output.Write(code)
+
+	for _, table := range this.Tables {
+		name := strings.ToLower(table.Name)
+		var file bytes.Buffer
+		if err := this.render(&file, "file.tpl", this); err != nil {
+			return err
+		}
+		file.Write(split[table].Bytes())
+		code, err := pruneImports(file.Bytes())
+		if err != nil {
+			return fmt.Errorf("%s_gen.go: %v", name, err)
+		}
+		this.Files[name+"_gen.go"] = code
+
+		var stub bytes.Buffer
+		if err := this.render(&stub, "custom.tpl", table); err != nil {
+			return err
+		}
+		this.Stubs[name+".go"] = stub.Bytes()
+	}
+	return nil
+}
+
+// drop the imports not referenced by the code and format it
+func pruneImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// unresolved identifiers in selectors are package names
+	used := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	// rewrite the import declarations with the used packages only
+	var out bytes.Buffer
+	offset := 0
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		var imports []string
+		for _, spec := range gen.Specs {
+			imp := spec.(*ast.ImportSpec)
+			path, _ := strconv.Unquote(imp.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			if name == "_" || used[name] {
+				imports = append(imports, string(src[fset.Position(imp.Pos()).Offset:fset.Position(imp.End()).Offset]))
+			}
+		}
+		out.Write(src[offset:fset.Position(gen.Pos()).Offset])
+		out.WriteString("import (\n" + strings.Join(imports, "\n") + "\n)")
+		offset = fset.Position(gen.End()).Offset
+	}
+	out.Write(src[offset:])
+	return format.Source(out.Bytes())
+}
+
+// header of the generated files
+const generatedHeader = "// Autogenerated by gomgen"
+
+// generated <table>_gen.go files in dir that are not generated any
+// more, like the files of dropped tables
+func (this *Generator) staleFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_gen.go"))
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if _, ok := this.Files[name]; ok {
+			continue
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(code, []byte(generatedHeader)) {
+			stale = append(stale, name)
+		}
+	}
+	return stale, nil
+}
+
+// write model.go and the Files into dir and remove the stale generated
+// files. Stubs are only written when they do not exist yet
+func (this *Generator) Write(dir string) error {
+	stale, err := this.staleFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "model.go"), this.Output.Bytes(), 0644); err != nil {
+		return err
+	}
+	for name, code := range this.Files {
+		if err := os.WriteFile(filepath.Join(dir, name), code, 0644); err != nil {
+			return err
+		}
+	}
+	for name, code := range this.Stubs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.WriteFile(path, code, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compare the generated files with the files in dir without writing
+// anything. Returns the unified diffs of the differing files sorted by
+// name. Missing stubs are reported, existing ones are hand written.
+// Stale generated <table>_gen.go files of dropped tables are reported as
+// removed, Write deletes them
+func (this *Generator) Check(dir string) ([]string, error) {
+	files := map[string][]byte{"model.go": this.Output.Bytes()}
+	for name, code := range this.Files {
+		files[name] = code
+	}
+	stale, err := this.staleFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		files[name] = nil
+	}
+	for name, code := range this.Stubs {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			files[name] = code
+		}
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diffs []string
+	for _, name := range names {
+		current, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if diff := unifiedDiff(name, string(current), string(files[name])); diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
@@ -4,7 +4,11 @@ import (
 	"bitbucket.org/pkg/inflect"
 	"bytes"
 	"database/sql"
+	"fmt"
 	"go/format"
+	"go/token"
+	"math"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -12,25 +16,44 @@ import (
 // Gomgen generator is the primary interface for scanning,
 // analyzing and generating models with gomgen
 type Generator struct {
-	Db      *sql.DB
-	Schema  string
-	Tables  []*Table
-	Imports map[string]bool
-	Output  *bytes.Buffer
+	Db       *sql.DB
+	Schema   string
+	Config   *Config
+	Analyzer Analyzer  // source of the tables. The database by default
+	Dialect  Dialect   // flavour of the DDL. MySQL by default
+	Snapshot *Snapshot // tables as found by the Analyzer, set by Analyse
+	Tables   []*Table
+	Imports  map[string]bool
+	Output   *bytes.Buffer
+	Files    map[string][]byte // outputs of Config.Extra and Config.SplitFiles by file name
+	Stubs    map[string][]byte // files created only when missing
+
+	// parsed templates
+	templates *template.Template
+
+	// registered callbacks
+	hooks hooks
 }
 
 // create and initialize new Gomgen object
 func NewGenerator(db *sql.DB, schema string) *Generator {
 	return &Generator{
-		Db:     db,
-		Schema: schema,
-		Tables: nil,
+		Db:       db,
+		Schema:   schema,
+		Config:   NewConfig(),
+		Analyzer: &Mysql{},
+		Dialect:  &Mysql{},
+		Tables:   nil,
 		Imports: map[string]bool{
+			"context":      true,
 			"database/sql": true,
 			"errors":       true,
 			"fmt":          true,
+			"strings":      true,
 		},
 		Output: &bytes.Buffer{},
+		Files:  map[string][]byte{},
+		Stubs:  map[string][]byte{},
 	}
 }
 
@@ -46,25 +69,155 @@ func (this *Generator) GetTable(name string) *Table {
 
 // Investigate the database
 func (this *Generator) Analyse() error {
-	mysql := &Mysql{}
-	return mysql.Analyze(this)
+	if err := this.Analyzer.Analyze(this); err != nil {
+		return err
+	}
+	this.Snapshot = NewSnapshot(this)
+	if err := this.applyTypes(); err != nil {
+		return err
+	}
+	this.linkRelations()
+	this.applyConventions()
+	return this.runAnalyseHooks()
+}
+
+// replace the column types with the custom types in Config.Types
+func (this *Generator) applyTypes() error {
+	for _, table := range this.Tables {
+		for _, field := range table.Fields {
+			for i := range this.Config.Types {
+				mapping := &this.Config.Types[i]
+				if !mapping.match(table, field) {
+					continue
+				}
+				// generated ids are assigned from int64
+				if mapping.Scanner && field.AutoInc {
+					return fmt.Errorf("%s.%s: auto increment column needs a type converted from int64, not a sql.Scanner", table.Name, field.RealName)
+				}
+				// other types are converted from the built-in type
+				if !mapping.Scanner {
+					switch field.Type {
+					case GoInt, GoFloat64, GoBool, GoString:
+						field.Convert = field.GoType
+					default:
+						return fmt.Errorf("%s.%s: %s needs to implement sql.Scanner", table.Name, field.RealName, mapping.GoType)
+					}
+				}
+				field.Type = GoCustom
+				field.GoType = mapping.GoType
+				if mapping.Import != "" {
+					this.Imports[mapping.Import] = true
+				}
+				this.Imports["reflect"] = true
+				break
+			}
+		}
+	}
+
+	// scanner types can not be converted, foreign keys need the same
+	// type on both ends
+	for _, table := range this.Tables {
+		for _, rel := range table.Relations {
+			src, dst := rel.Column, rel.TargetColumn
+			if rel.Type != OneToOne || src.GoType == dst.GoType {
+				continue
+			}
+			if src.Type == GoCustom && src.Convert == "" || dst.Type == GoCustom && dst.Convert == "" {
+				return fmt.Errorf("%s.%s: foreign key type %s differs from %s.%s type %s", table.Name, src.RealName, src.GoType, rel.TargetEntity.Name, dst.RealName, dst.GoType)
+			}
+		}
+	}
+	return nil
+}
+
+// mark the special purpose columns configured in Config
+func (this *Generator) applyConventions() {
+	for _, table := range this.Tables {
+		// nullable fields as pointers to the plain types
+		for _, field := range table.Fields {
+			if plain, ok := nullPlainTypes[field.Type]; ok && this.Config.nullPointer(table, field) {
+				field.Pointer = true
+				field.GoType = "*" + GoTypeMap[plain]
+			}
+		}
+		// optimistic locking needs a plain integer column
+		if field := this.Config.match(table, this.Config.VersionColumns); field != nil && field.Type == GoInt && !field.Primary {
+			table.Version = field
+		}
+		// soft delete needs a nullable timestamp
+		if field := this.Config.match(table, this.Config.SoftDeleteColumns); field != nil && field.Type == GoNullTime {
+			table.SoftDelete = field
+		}
+		// managed timestamps
+		if field := this.Config.match(table, this.Config.CreatedColumns); field != nil && isTime(field) {
+			table.Created = field
+		}
+		if field := this.Config.match(table, this.Config.UpdatedColumns); field != nil && isTime(field) {
+			table.Updated = field
+		}
+	}
+}
+
+// check if field holds date and time
+func isTime(field *Field) bool {
+	return field.Type == GoTime || field.Type == GoNullTime
 }
 
 // Generate the model source code
 func (this *Generator) Generate() error {
+	if err := this.loadTemplates(); err != nil {
+		return err
+	}
+
 	// entities
+	steps := []func(table *Table) error{
+		this.buildTags,
+		this.genStruct,
+		this.genMeta,
+		this.genScanFn,
+		this.genValidateFn,
+		this.genFindFn,
+		this.genSaveFn,
+		this.genBulkInsertFn,
+		this.genDeleteFn,
+		this.genRelFn,
+	}
+	if this.Config.JsonMethods {
+		steps = append(steps, this.genJsonFn)
+	}
+	this.Imports[this.Config.Runtime] = true
+	shared := this.Output
+	split := map[*Table]*bytes.Buffer{}
 	for _, table := range this.Tables {
-		this.genStruct(table)
-		this.genScanFn(table)
-		this.genFindFn(table)
-		this.genSaveFn(table)
-		this.genRelFn(table)
+		// code of the table goes to its own file
+		if this.Config.SplitFiles {
+			this.Output = &bytes.Buffer{}
+			split[table] = this.Output
+		}
+		for _, step := range steps {
+			if err := step(table); err != nil {
+				return fmt.Errorf("table %s: %v", table.Name, err)
+			}
+		}
+		for _, fn := range this.hooks.tableWriters {
+			if err := fn(this, table, this.Output); err != nil {
+				return fmt.Errorf("table %s: %v", table.Name, err)
+			}
+		}
+	}
+	this.Output = shared
+	for _, fn := range this.hooks.writers {
+		if err := fn(this, this.Output); err != nil {
+			return err
+		}
 	}
 
 	// generate the header
 	var header = bytes.Buffer{}
-	var t = template.Must(template.New("headerTpl").Parse(headerTpl))
-	if err := t.Execute(&header, this); err != nil {
+	if err := this.render(&header, "header.tpl", this); err != nil {
+		return err
+	}
+	if err := this.genSchema(&header); err != nil {
 		return err
 	}
 
@@ -81,44 +234,63 @@ func (this *Generator) Generate() error {
 		this.Output.Write(c)
 	}
 
+	// separate files of the tables
+	if this.Config.SplitFiles {
+		if err := this.genSplitFiles(split); err != nil {
+			return err
+		}
+	}
+
+	// user outputs
+	if err := this.genExtras(); err != nil {
+		return err
+	}
+
 	// done :)
 	return nil
 }
 
 // generate the table entity
 func (this *Generator) genStruct(table *Table) error {
-	var t = template.Must(template.New("entityStructTpl").Parse(entityStructTpl))
-	return t.Execute(this.Output, table)
+	return this.render(this.Output, "struct.tpl", table)
 }
 
 // Generate scan function
 func (this *Generator) genScanFn(table *Table) error {
-	// the template
-	var t = template.Must(template.New("scanEntity").Parse(scanEntityTpl))
-
-	// template params
-	type templateParams struct {
-		*Table
-		Vars   map[string]string // declared extra variables
-		Params string            // params for the Scan method
-		Inits  []string          // value loads for the variables
-	}
-	p := &templateParams{}
+	p := &ScanData{}
 	p.Table = table
 	p.Vars = make(map[string]string)
 
+	// declare extra variable of the type
+	declare := func(typ, name string) {
+		if _, ok := p.Vars[typ]; ok {
+			p.Vars[typ] += ", " + name
+		} else {
+			p.Vars[typ] = name
+		}
+	}
+
 	// process fields
 	var params []string
 	for _, field := range table.Fields {
 		if field.Type == GoTime {
-			if _, ok := p.Vars["string"]; ok {
-				p.Vars["string"] += ", " + field.Name
-			} else {
-				p.Vars["string"] = field.Name
-			}
+			declare("string", field.Name)
 			params = append(params, "&"+field.Name)
 			init := "this." + field.Name + ", _ = time.Parse(\"" + field.Format + "\", " + field.Name + ")"
 			p.Inits = append(p.Inits, init)
+		} else if field.Type == GoNullTime {
+			parse := "parseNullTime"
+			if field.Pointer {
+				parse = "parsePtrTime"
+			}
+			declare("sql.NullString", field.Name)
+			params = append(params, "&"+field.Name)
+			init := "this." + field.Name + " = " + parse + "(\"" + field.Format + "\", " + field.Name + ")"
+			p.Inits = append(p.Inits, init)
+		} else if field.Convert != "" {
+			declare(field.Convert, field.Name)
+			params = append(params, "&"+field.Name)
+			p.Inits = append(p.Inits, "this."+field.Name+" = "+field.GoType+"("+field.Name+")")
 		} else {
 			params = append(params, "&this."+field.Name)
 		}
@@ -126,16 +298,18 @@ func (this *Generator) genScanFn(table *Table) error {
 	p.Params = strings.Join(params, ", ")
 
 	// process
-	return t.Execute(this.Output, p)
+	return this.render(this.Output, "scan.tpl", p)
 }
 
 // find function
 func (this *Generator) genFindFn(table *Table) error {
-	type params struct {
-		*Table
-		IdentityField *Field
+	p := &FindData{Table: table}
+
+	// soft deleted rows are filtered out through the query options
+	p.Select = table.EntitySingular + `Meta.Select("")`
+	if table.SoftDelete != nil {
+		p.Select = table.EntitySingular + `Meta.Select(option.from("` + table.EscapedName + `", "` + table.SoftDelete.EscapedName + `"))`
 	}
-	p := params{Table: table}
 
 	// singly identifiable table
 	if len(table.Identity) == 1 {
@@ -147,104 +321,576 @@ func (this *Generator) genFindFn(table *Table) error {
 	}
 
 	// render
-	var t = template.Must(template.New("findEntityTpl").Parse(findEntityTpl))
-	return t.Execute(this.Output, p)
-	return nil
+	return this.render(this.Output, "find.tpl", p)
 }
 
-// generate the table entity
-func (this *Generator) genSaveFn(table *Table) error {
-	type params struct {
-		*Table
-		IdCheck      string
-		InsertCols   string
-		InsertVals   string
-		UpdateVals   string
-		Where        string
-		UpdateParams string
-		InsertParams string
-		AutoIncField *Field
-	}
-	p := &params{Table: table}
-
-	// identity check to know if insert or update
-	for _, field := range table.Identity {
-		if len(p.IdCheck) > 0 {
-			p.IdCheck += " && "
+// sql types holding character strings
+var sqlTextTypes = map[string]bool{
+	"char": true, "varchar": true, "tinytext": true, "text": true, "mediumtext": true, "longtext": true,
+}
+
+// generate MarshalJSON and UnmarshalJSON
+func (this *Generator) genJsonFn(table *Table) error {
+	p := &JsonData{Table: table}
+	p.Name = strings.ToLower(table.EntitySingular[:1]) + table.EntitySingular[1:] + "JSON"
+
+	// nullable types are pointers, times are formatted strings
+	nulls := map[GoType][2]string{
+		GoNullInt:     {"int64", "Int64"},
+		GoNullFloat64: {"float64", "Float64"},
+		GoNullBool:    {"bool", "Bool"},
+		GoNullString:  {"string", "String"},
+	}
+	for _, field := range table.Fields {
+		if field.JsonTag == "-" {
+			continue
 		}
-		p.IdCheck += "this." + field.Name + " == "
-		if field.Type == GoInt || field.Type == GoFloat64 {
-			p.IdCheck += "0"
-		} else if field.Type == GoString {
-			p.IdCheck += "\"\""
+		// decoding leaves the fields missing from the json alone. Null
+		// clears the nullable fields, empty times count as null
+		f := JsonField{Field: field, Type: field.GoType, DecodeType: field.GoType}
+		name := field.Name
+		value := "data." + name + ".Value"
+		parse := "t, err := time.Parse(JsonTimeLayout, *" + value + ")\nif err != nil {\nreturn err\n}\n"
+		if field.Pointer && field.Type == GoNullTime {
+			f.Type, f.DecodeType = "*string", "string"
+			f.Marshal = "if this." + name + " != nil {\nvalue := this." + name + ".Format(JsonTimeLayout)\ndata." + name + " = &value\n}"
+			f.Unmarshal = "if data." + name + ".Set {\nthis." + name + " = nil\nif " + value + " != nil && *" + value + " != \"\" {\n" + parse + "this." + name + " = &t\n}\n}"
+		} else if field.Pointer {
+			f.DecodeType = strings.TrimPrefix(field.GoType, "*")
+			f.Marshal = "data." + name + " = this." + name
+			f.Unmarshal = "if data." + name + ".Set {\nthis." + name + " = " + value + "\n}"
+		} else if null, ok := nulls[field.Type]; ok {
+			f.Type, f.DecodeType = "*"+null[0], null[0]
+			f.Marshal = "if this." + name + ".Valid {\nvalue := this." + name + "." + null[1] + "\ndata." + name + " = &value\n}"
+			f.Unmarshal = "if data." + name + ".Set {\nthis." + name + " = " + field.GoType + "{}\nif " + value + " != nil {\nthis." + name + " = " + field.GoType + "{" + null[1] + ": *" + value + ", Valid: true}\n}\n}"
+		} else if field.Type == GoTime {
+			f.Type, f.DecodeType = "string", "string"
+			f.Marshal = "data." + name + " = this." + name + ".Format(JsonTimeLayout)"
+			f.Unmarshal = "if " + value + " != nil && *" + value + " != \"\" {\n" + parse + "this." + name + " = t\n}"
+		} else if field.Type == GoNullTime {
+			f.Type, f.DecodeType = "*string", "string"
+			f.Marshal = "if this." + name + ".Valid {\nvalue := this." + name + ".Time.Format(JsonTimeLayout)\ndata." + name + " = &value\n}"
+			f.Unmarshal = "if data." + name + ".Set {\nthis." + name + " = sql.NullTime{}\nif " + value + " != nil && *" + value + " != \"\" {\n" + parse + "this." + name + " = sql.NullTime{Time: t, Valid: true}\n}\n}"
+		} else {
+			f.Marshal = "data." + name + " = this." + name
+			f.Unmarshal = "if " + value + " != nil {\nthis." + name + " = *" + value + "\n}"
 		}
+		p.Fields = append(p.Fields, f)
+	}
+	this.Imports["encoding/json"] = true
 
-		if len(p.Where) > 0 {
-			p.Where += " AND "
+	// render
+	return this.render(this.Output, "json.tpl", p)
+}
+
+// generate the descriptor of the entity for the runtime
+func (this *Generator) genMeta(table *Table) error {
+	p := &MetaData{Table: table}
+
+	var columns, identity, inserted, values []string
+	for _, field := range table.Fields {
+		columns = append(columns, strconv.Quote(field.EscapedName))
+		if field.Primary {
+			identity = append(identity, strconv.Quote(field.EscapedName))
 		}
-		p.Where += field.EscapedName + " = ?"
+		// auto increment values are assigned by the database
+		if field.AutoInc {
+			p.AutoIncField = field
+		} else {
+			inserted = append(inserted, strconv.Quote(field.EscapedName))
+			values = append(values, bindParam("this", field))
+		}
+	}
+	p.Columns = strings.Join(columns, ", ")
+	p.Identity = strings.Join(identity, ", ")
+	p.Inserted = strings.Join(inserted, ", ")
+	p.Values = strings.Join(values, ", ")
+	if p.AutoIncField != nil {
+		p.IdValue = idValue(p.AutoIncField, "id")
+	}
+
+	// render
+	return this.render(this.Output, "meta.tpl", p)
+}
 
-		if len(p.UpdateParams) > 0 {
-			p.UpdateParams += ", "
+// generate Validate method checking the column constraints
+func (this *Generator) genValidateFn(table *Table) error {
+	p := &ValidateData{Table: table}
+
+	for _, field := range table.Fields {
+		// value and validity of nullable fields
+		value, valid := "this."+field.Name, ""
+		switch {
+		case field.Pointer:
+			value, valid = "*this."+field.Name, "this."+field.Name+" != nil && "
+		case field.Type == GoNullString:
+			value, valid = "this."+field.Name+".String", "this."+field.Name+".Valid && "
+		case field.Type == GoNullInt:
+			value, valid = "this."+field.Name+".Int64", "this."+field.Name+".Valid && "
 		}
-		p.UpdateParams += "this." + field.Name
+		add := func(cond, message string) {
+			p.Checks = append(p.Checks, Check{field, valid + cond, strconv.Quote(message)})
+		}
+
+		switch field.Type {
+		case GoString, GoNullString:
+			if len(field.Values) > 0 && field.BaseType == "enum" {
+				var values []string
+				for _, value := range field.Values {
+					values = append(values, strconv.Quote(value))
+				}
+				add("!oneOf("+value+", "+strings.Join(values, ", ")+")", "must be one of "+strings.Join(field.Values, ", "))
+				continue
+			}
+			if !sqlTextTypes[field.BaseType] {
+				continue
+			}
+			if field.Type == GoString && !field.Default.Valid {
+				add(value+` == ""`, "is required")
+			}
+			if field.Length > 0 {
+				this.Imports["unicode/utf8"] = true
+				add("utf8.RuneCountInString("+value+") > "+strconv.Itoa(field.Length), "is longer than "+strconv.Itoa(field.Length)+" characters")
+			}
+		case GoInt, GoNullInt:
+			if !field.HasRange {
+				continue
+			}
+			var conds []string
+			if field.Min != math.MinInt64 {
+				conds = append(conds, value+" < "+strconv.FormatInt(field.Min, 10))
+			}
+			if field.Max != math.MaxInt64 {
+				conds = append(conds, value+" > "+strconv.FormatInt(field.Max, 10))
+			}
+			if len(conds) == 0 {
+				continue
+			}
+			cond := strings.Join(conds, " || ")
+			if valid != "" && len(conds) > 1 {
+				cond = "(" + cond + ")"
+			}
+			add(cond, "must be between "+strconv.FormatInt(field.Min, 10)+" and "+strconv.FormatInt(field.Max, 10))
+		}
+	}
 
+	// render
+	return this.render(this.Output, "validate.tpl", p)
+}
+
+// generate Insert, Update, Upsert and Save methods
+func (this *Generator) genSaveFn(table *Table) error {
+	p := &SaveData{Table: table}
+
+	// managed timestamps
+	if table.Created != nil {
+		p.SetCreated = "this." + table.Created.Name + " = " + timeValue(table.Created, "now")
+	}
+	if table.Updated != nil {
+		p.SetUpdated = "this." + table.Updated.Name + " = " + timeValue(table.Updated, "now")
+	}
+
+	// identity is used for the WHERE clause of the updates
+	var where, whereParams []string
+	for _, field := range table.Identity {
+		where = append(where, field.EscapedName+" = ?")
+		whereParams = append(whereParams, bindParam("this", field))
 		if field.AutoInc {
 			p.AutoIncField = field
 		}
 	}
+	p.Where = strings.Join(where, " AND ")
 
-	// insert / update cols
-	first := true
+	// columns for the upsert and update statements
+	var upsertCols, upsertVals, upsertParams, upsertSet []string
 	for _, field := range table.Fields {
-		// skip primary key columns
-		if field.Primary {
+		upsertCols = append(upsertCols, field.EscapedName)
+		upsertVals = append(upsertVals, "?")
+		upsertParams = append(upsertParams, bindParam("this", field))
+
+		// version is incremented by the update itself
+		if field == table.Version {
+			upsertSet = append(upsertSet, field.EscapedName+" = "+field.EscapedName+" + 1")
 			continue
 		}
-		// separate
-		if !first {
-			p.InsertCols += ", "
-			p.InsertVals += ", "
-			p.UpdateVals += ", "
-			p.InsertParams += ", "
-		}
-		first = false
 
-		p.InsertCols += field.EscapedName
-		p.InsertVals += "?"
-		p.UpdateVals += field.EscapedName + " = ?"
+		// creation time is kept when the row exists
+		if field == table.Created {
+			p.Changes = append(p.Changes, Change{
+				Cond:  changedCond(field),
+				Col:   field.EscapedName,
+				Param: bindParam("this", field),
+			})
+			continue
+		}
 
-		p.InsertParams += "this." + field.Name
-		if field.Type == GoTime {
-			p.InsertParams += ".Format(\"" + field.Format + "\")"
+		// primary key columns are never updated
+		if !field.Primary {
+			p.Changes = append(p.Changes, Change{
+				Cond:  changedCond(field),
+				Col:   field.EscapedName,
+				Param: bindParam("this", field),
+			})
+			upsertSet = append(upsertSet, field.EscapedName+" = VALUES("+field.EscapedName+")")
 		}
 	}
 
-	// update params
-	p.UpdateParams = p.InsertParams + ", " + p.UpdateParams
+	// on duplicate key get back the existing auto increment value.
+	// When there is nothing to update, keep the statement valid with a no-op
+	if p.AutoIncField != nil {
+		name := p.AutoIncField.EscapedName
+		upsertSet = append([]string{name + " = LAST_INSERT_ID(" + name + ")"}, upsertSet...)
+	} else if len(upsertSet) == 0 && len(table.Identity) > 0 {
+		name := table.Identity[0].EscapedName
+		upsertSet = append(upsertSet, name+" = "+name)
+	}
+
+	p.UpsertCols = strings.Join(upsertCols, ", ")
+	p.UpsertVals = strings.Join(upsertVals, ", ")
+	p.UpsertParams = strings.Join(upsertParams, ", ")
+	p.UpsertSet = strings.Join(upsertSet, ", ")
+	p.WhereParams = strings.Join(whereParams, ", ")
+	p.VersionField = table.Version
+	if p.AutoIncField != nil {
+		p.IdValue = idValue(p.AutoIncField, "lastId")
+	}
+
+	// update needs an identity and something to update. Save can only
+	// pick between insert and update when the identity is a single
+	// auto increment column
+	p.HasUpdate = len(table.Identity) > 0 && len(p.Changes) > 0
+	p.HasSave = p.HasUpdate && len(table.Identity) == 1 && p.AutoIncField != nil && (p.AutoIncField.Type == GoInt || p.AutoIncField.Convert == "int64")
 
 	// render the template
-	var t = template.Must(template.New("entitySaveTpl").Parse(entitySaveTpl))
-	return t.Execute(this.Output, p)
+	return this.render(this.Output, "save.tpl", p)
 }
 
-const entityOneToOneTpl = `
-// find related {{ .TargetEntity.EntitySingular }}
-func (this *{{ .Table.EntitySingular }}) Find{{ .Name }}() (*{{ .TargetEntity.EntitySingular }}, error) {
-	sql := "WHERE {{ .TargetEntity.EscapedName }}.{{ .TargetColumn.EscapedName }} = ?"
-	return Find{{ .TargetEntity.EntitySingular }}(sql, this.{{ .Column.Name }})
+// generate Delete and HardDelete methods
+func (this *Generator) genDeleteFn(table *Table) error {
+	if len(table.Identity) == 0 {
+		return nil
+	}
+	p := &DeleteData{Table: table}
+
+	var where, whereParams []string
+	for _, field := range table.Identity {
+		where = append(where, field.EscapedName+" = ?")
+		whereParams = append(whereParams, bindParam("this", field))
+	}
+	p.Where = strings.Join(where, " AND ")
+	p.WhereParams = strings.Join(whereParams, ", ")
+
+	// render
+	return this.render(this.Output, "delete.tpl", p)
+}
+
+// generate multi-row insert for the table
+func (this *Generator) genBulkInsertFn(table *Table) error {
+	p := &BulkInsertData{Table: table}
+	p.Touch = table.Created != nil || table.Updated != nil
+
+	var cols, row, values []string
+	for _, field := range table.Fields {
+		if field.AutoInc {
+			p.AutoIncField = field
+			continue
+		}
+		cols = append(cols, field.EscapedName)
+		row = append(row, "?")
+		values = append(values, bindParam("entity", field))
+	}
+	p.Cols = strings.Join(cols, ", ")
+	p.Row = "(" + strings.Join(row, ", ") + ")"
+	p.Params = strings.Join(values, ", ")
+	if p.AutoIncField != nil {
+		p.IdValue = idValue(p.AutoIncField, "firstId + int64(i)")
+	}
+
+	// render
+	return this.render(this.Output, "bulk_insert.tpl", p)
+}
+
+// condition checking if the field differs from the snapshot
+func changedCond(field *Field) string {
+	if field.Pointer {
+		value, snapshot := "this."+field.Name, "this.snapshot."+field.Name
+		cond := "*" + value + " != *" + snapshot
+		if field.Type == GoNullTime {
+			cond = "!" + value + ".Equal(*" + snapshot + ")"
+		}
+		return "(" + value + " == nil) != (" + snapshot + " == nil) || " + value + " != nil && " + cond
+	} else if field.Type == GoCustom {
+		return "!equal(this." + field.Name + ", this.snapshot." + field.Name + ")"
+	} else if field.Type == GoTime {
+		return "!this." + field.Name + ".Equal(this.snapshot." + field.Name + ")"
+	} else if field.Type == GoNullTime {
+		return "this." + field.Name + ".Valid != this.snapshot." + field.Name + ".Valid || !this." + field.Name + ".Time.Equal(this.snapshot." + field.Name + ".Time)"
+	}
+	return "this." + field.Name + " != this.snapshot." + field.Name
+}
+
+// expression assigning time value to the field
+func timeValue(field *Field, value string) string {
+	if field.Pointer {
+		return "ptr(" + value + ")"
+	} else if field.Type == GoNullTime {
+		return "sql.NullTime{Time: " + value + ", Valid: true}"
+	}
+	return value
+}
+
+// expression converting the int64 id to the type of the field
+func idValue(field *Field, value string) string {
+	if field.Convert != "" {
+		return field.GoType + "(" + value + ")"
+	}
+	return value
+}
+
+// built-in type of the field. Custom types converted from a built-in
+// type count as that type
+func plainType(field *Field) GoType {
+	if field.Convert != "" {
+		for t, name := range GoTypeMap {
+			if name == field.Convert {
+				return t
+			}
+		}
+	}
+	return field.Type
+}
+
+// expression binding the field value of recv as a query parameter
+func bindParam(recv string, field *Field) string {
+	if field.Convert != "" {
+		return field.Convert + "(" + recv + "." + field.Name + ")"
+	} else if field.Type == GoTime {
+		return recv + "." + field.Name + ".Format(\"" + field.Format + "\")"
+	} else if field.Type == GoNullTime && field.Pointer {
+		return "formatPtrTime(\"" + field.Format + "\", " + recv + "." + field.Name + ")"
+	} else if field.Type == GoNullTime {
+		return "formatNullTime(\"" + field.Format + "\", " + recv + "." + field.Name + ")"
+	}
+	return recv + "." + field.Name
 }
-`
 
 // generate relations
 func (this *Generator) genRelFn(table *Table) error {
-	var t = template.Must(template.New("entityOneToOneTpl").Parse(entityOneToOneTpl))
 	for _, rel := range table.Relations {
-		return t.Execute(this.Output, rel)
+		p := relationData(rel)
+
+		// accessor
+		name := "one_to_one.tpl"
+		if rel.Type == OneToMany {
+			name = "one_to_many.tpl"
+		} else if rel.Type == ManyToMany {
+			name = "many_to_many.tpl"
+		}
+		if err := this.render(this.Output, name, p); err != nil {
+			return err
+		}
+
+		// cached access
+		if err := this.render(this.Output, "cached_relation.tpl", p); err != nil {
+			return err
+		}
+
+		// preloading
+		if err := this.render(this.Output, "load_relation.tpl", p); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// template data of the relation
+func relationData(rel *Relation) *RelationData {
+	p := &RelationData{Relation: rel}
+	p.Single = rel.Type == OneToOne
+	p.Through = rel.Type == ManyToMany
+	p.Key, p.KeyValid = keyExpr("entity", rel.Column)
+	p.ItemKey, p.ItemValid = keyExpr("item", rel.TargetColumn)
+	if rel.Type == ManyToMany {
+		p.LinkSrc, p.LinkSrcValid = keyExpr("link", rel.MiddleSrcColumn)
+		p.LinkDst, p.LinkDstValid = keyExpr("link", rel.MiddleDstColumn)
+	}
+	if rel.Type == OneToOne {
+		p.SetKey = assignExpr("this", rel.Column, "related", rel.TargetColumn)
+	} else if rel.Type == OneToMany {
+		p.SetKey = assignExpr("item", rel.TargetColumn, "this", rel.Column)
+	}
+	p.Nullable = rel.Column.Nullable
+	return p
+}
+
+// assignment of the src field value to the dst field, wrapping and
+// unwrapping the nullable types
+func assignExpr(dstRecv string, dst *Field, srcRecv string, src *Field) string {
+	value := srcRecv + "." + src.Name
+	if dst.GoType == src.GoType && dst.Type == GoCustom {
+		return dstRecv + "." + dst.Name + " = " + value
+	}
+
+	// custom types are converted through their built-in type
+	if src.Convert != "" {
+		value = src.Convert + "(" + value + ")"
+	}
+	dstType, srcType := plainType(dst), plainType(src)
+	switch {
+	case dst.Pointer && src.Pointer:
+		value = "ptr(*" + value + ")"
+	case dst.Pointer:
+		value = "ptr(" + value + ")"
+	case src.Pointer:
+		value = "*" + value
+	case dstType == GoNullInt && srcType == GoInt:
+		value = "sql.NullInt64{Int64: " + value + ", Valid: true}"
+	case dstType == GoNullString && srcType == GoString:
+		value = "sql.NullString{String: " + value + ", Valid: true}"
+	case dstType == GoInt && srcType == GoNullInt:
+		value += ".Int64"
+	case dstType == GoString && srcType == GoNullString:
+		value += ".String"
+	}
+	if dst.Convert != "" {
+		value = dst.GoType + "(" + value + ")"
+	}
+	return dstRecv + "." + dst.Name + " = " + value
+}
+
+// expression of the field value usable as a map key and the
+// condition when it is present. Condition is empty for not null fields
+func keyExpr(recv string, field *Field) (value, valid string) {
+	// custom types are keyed by their built-in value to match the
+	// other end of the relation
+	if field.Convert != "" {
+		return field.Convert + "(" + recv + "." + field.Name + ")", ""
+	}
+	if field.Pointer {
+		return "*" + recv + "." + field.Name, "(" + recv + "." + field.Name + " != nil)"
+	}
+	switch field.Type {
+	case GoNullInt:
+		return recv + "." + field.Name + ".Int64", recv + "." + field.Name + ".Valid"
+	case GoNullString:
+		return recv + "." + field.Name + ".String", recv + "." + field.Name + ".Valid"
+	}
+	return recv + "." + field.Name, ""
+}
+
+// connect the tables through the relations found by the analyzer.
+// Foreign keys get the inverse one-to-many relation and tables
+// only connecting two other tables are turned into many-to-many
+func (this *Generator) linkRelations() {
+	// inverse relations
+	for _, table := range this.Tables {
+		for _, rel := range table.Relations {
+			if rel.Type != OneToOne || rel.TargetEntity == nil {
+				continue
+			}
+			inverse := &Relation{
+				Name:         uniqueRelationName(rel.TargetEntity, table.EntityPlural, rel.Name),
+				Type:         OneToMany,
+				Table:        rel.TargetEntity,
+				Column:       rel.TargetColumn,
+				TargetEntity: table,
+				TargetColumn: rel.Column,
+			}
+			rel.TargetEntity.Relations = append(rel.TargetEntity.Relations, inverse)
+		}
+	}
+
+	// connecting tables have two foreign keys making up all the columns
+	for _, middle := range this.Tables {
+		var links []*Relation
+		for _, rel := range middle.Relations {
+			if rel.Type == OneToOne && rel.TargetEntity != nil {
+				links = append(links, rel)
+			}
+		}
+		if len(links) != 2 || len(middle.Fields) != 2 || links[0].Column == links[1].Column {
+			continue
+		}
+		for i, link := range links {
+			other := links[1-i]
+			rel := &Relation{
+				Name:            uniqueRelationName(link.TargetEntity, other.TargetEntity.EntityPlural, link.Name),
+				Type:            ManyToMany,
+				Table:           link.TargetEntity,
+				Column:          link.TargetColumn,
+				TargetEntity:    other.TargetEntity,
+				TargetColumn:    other.TargetColumn,
+				MiddleEntity:    middle,
+				MiddleSrcColumn: link.Column,
+				MiddleDstColumn: other.Column,
+			}
+			rel.Table.Relations = append(rel.Table.Relations, rel)
+		}
+	}
+
+	// relation methods must not clash with the struct fields
+	for _, table := range this.Tables {
+		for _, rel := range table.Relations {
+			renameClashingRelation(table, rel)
+		}
+	}
+}
+
+// relation name not yet used in the table. Conflicting names
+// are qualified with the name of the relation they come from
+func uniqueRelationName(table *Table, name, via string) string {
+	for _, rel := range table.Relations {
+		if strings.EqualFold(rel.Name, name) {
+			return name + "By" + via
+		}
+	}
+	return name
+}
+
+// rename the relation when its getter, setter, finder or cache would
+// clash with a field of the table. The target entity is added to the
+// name, and a number when that is taken as well
+func renameClashingRelation(table *Table, rel *Relation) {
+	if !relationClashes(table, rel, rel.Name) {
+		return
+	}
+	target := rel.TargetEntity.EntityPlural
+	if rel.Type == OneToOne {
+		target = rel.TargetEntity.EntitySingular
+	}
+	base := rel.Name + target
+	if strings.EqualFold(rel.Name, target) {
+		base = "Related" + target
+	}
+	name := base
+	for i := 2; relationClashes(table, rel, name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	rel.Name = name
+}
+
+// check if the relation name clashes with the struct fields or the
+// other relations of the table
+func relationClashes(table *Table, rel *Relation, name string) bool {
+	if cacheName(name) == "snapshot" {
+		return true
+	}
+	for _, fields := range [][]*Field{table.Fields, table.Virtual} {
+		for _, field := range fields {
+			switch field.Name {
+			case name, "Set" + name, "Reload" + name, "Find" + name:
+				return true
+			}
+		}
+	}
+	for _, other := range table.Relations {
+		if other != rel && (strings.EqualFold(other.Name, name) || other.CacheName() == cacheName(name)) {
+			return true
+		}
+	}
+	return false
+}
+
 // specify the relation type between the entities
 type RelationType int
 
@@ -257,10 +903,12 @@ const (
 // represent a relation between the tables
 type Relation struct {
 	Name            string
+	Type            RelationType
+	Constraint      string // foreign key name of the one-to-one relations
 	Table 			*Table
-	Column          *Field // null for many-to-many
+	Column          *Field // column of this entity
 	TargetEntity    *Table
-	TargetColumn    *Field // null for many-to-many
+	TargetColumn    *Field // column of the target entity
 	MiddleEntity    *Table // connecting table
 	MiddleSrcColumn *Field // point to this entity
 	MiddleDstColumn *Field // point to target entity
@@ -273,6 +921,28 @@ func NewRelation(name string) *Relation {
 	}
 }
 
+// name of the unexported struct field holding the loaded entities
+func (this *Relation) CacheName() string {
+	return cacheName(this.Name)
+}
+
+// lower cased relation name. Keywords like type get a Cache suffix
+func cacheName(name string) string {
+	name = strings.ToLower(name[:1]) + name[1:]
+	if token.IsKeyword(name) {
+		name += "Cache"
+	}
+	return name
+}
+
+// type of the loaded entities
+func (this *Relation) CacheType() string {
+	if this.Type == OneToOne {
+		return "*" + this.TargetEntity.EntitySingular
+	}
+	return "[]*" + this.TargetEntity.EntitySingular
+}
+
 // represent a database table
 type Table struct {
 	Name           string
@@ -283,6 +953,28 @@ type Table struct {
 	Fields         []*Field
 	Identity       []*Field
 	Relations      []*Relation
+	Version        *Field   // optimistic locking column
+	SoftDelete     *Field   // deletion timestamp column
+	Created        *Field   // creation timestamp column
+	Updated        *Field   // modification timestamp column
+	Virtual        []*Field // struct fields without a column
+	Indexes        []*Index
+}
+
+// table index
+type Index struct {
+	Name    string
+	Unique  bool
+	Primary bool
+	Fields  []*Field // columns in the index order
+}
+
+// add a struct field not stored in the database. It is not scanned,
+// saved, validated nor encoded by the json methods
+func (this *Table) AddVirtual(name, goType string) *Field {
+	field := &Field{Name: name, GoType: goType}
+	this.Virtual = append(this.Virtual, field)
+	return field
 }
 
 // create new table
@@ -321,6 +1013,8 @@ const (
 	GoNullFloat64
 	GoNullBool
 	GoNullString
+	GoNullTime
+	GoCustom
 )
 
 // map GoType constants to strings of actual types
@@ -334,6 +1028,16 @@ var GoTypeMap = map[GoType]string{
 	GoNullFloat64: "sql.NullFloat64",
 	GoNullBool:    "sql.NullBool",
 	GoNullString:  "sql.NullString",
+	GoNullTime:    "sql.NullTime",
+}
+
+// plain types of the nullable types used by the pointer null style
+var nullPlainTypes = map[GoType]GoType{
+	GoNullInt:     GoInt,
+	GoNullFloat64: GoFloat64,
+	GoNullBool:    GoBool,
+	GoNullString:  GoString,
+	GoNullTime:    GoTime,
 }
 
 // represent individual field in the table
@@ -349,6 +1053,28 @@ type Field struct {
 	AutoInc     bool
 	Comment     string
 	Format      string
+	SqlType     string   // column type as reported by the database
+	Key         string   // column key as reported by the database. PRI, UNI, MUL
+	Extra       string   // extra column info as reported by the database
+	BaseType    string   // sql type without the size and attributes
+	Length      int      // declared size. Maximum length of strings
+	Unsigned    bool     // unsigned numeric type
+	Values      []string // allowed enum and set values
+	HasRange    bool     // integer value must be between Min and Max
+	Min         int64
+	Max         int64
+	Tag         string // struct tag of the field
+	JsonTag     string // value of the json tag
+	Pointer     bool   // nullable field is a pointer to the plain type
+	Convert     string // built-in type custom type is converted from and to
+}
+
+// zero value of the field type
+func (this *Field) Zero() string {
+	if this.Pointer {
+		return "nil"
+	}
+	return this.GoType + "{}"
 }
 
 // the name of the field
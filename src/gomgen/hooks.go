@@ -0,0 +1,114 @@
+package gomgen
+
+import (
+	"io"
+)
+
+// callbacks registered by the programs embedding the generator
+type hooks struct {
+	afterAnalyse []func(gen *Generator) error
+	tables       []func(gen *Generator, table *Table) error
+	fields       []func(table *Table, field *Field) error
+	tableWriters []func(gen *Generator, table *Table, w io.Writer) error
+	writers      []func(gen *Generator, w io.Writer) error
+}
+
+// call fn at the end of Analyse, after the table and field mutators
+func (this *Generator) AfterAnalyse(fn func(gen *Generator) error) {
+	this.hooks.afterAnalyse = append(this.hooks.afterAnalyse, fn)
+}
+
+// call fn for every analysed table. Use it to rename the entities
+// and relations, add virtual fields or remove columns
+func (this *Generator) MutateTable(fn func(gen *Generator, table *Table) error) {
+	this.hooks.tables = append(this.hooks.tables, fn)
+}
+
+// call fn for every field of the analysed tables, after the table mutators
+func (this *Generator) MutateField(fn func(table *Table, field *Field) error) {
+	this.hooks.fields = append(this.hooks.fields, fn)
+}
+
+// call fn after the code of each table is generated to append more
+// code for the table. Needed packages can be added to Imports
+func (this *Generator) WriteTable(fn func(gen *Generator, table *Table, w io.Writer) error) {
+	this.hooks.tableWriters = append(this.hooks.tableWriters, fn)
+}
+
+// call fn after the code of all tables is generated to append more
+// code. Writers may also add whole files to Files
+func (this *Generator) WriteOutput(fn func(gen *Generator, w io.Writer) error) {
+	this.hooks.writers = append(this.hooks.writers, fn)
+}
+
+// run the mutators and the after analyse hooks
+func (this *Generator) runAnalyseHooks() error {
+	for _, table := range this.Tables {
+		for _, fn := range this.hooks.tables {
+			if err := fn(this, table); err != nil {
+				return err
+			}
+		}
+	}
+	for _, table := range this.Tables {
+		// fields can be removed by the hooks
+		fields := append([]*Field(nil), table.Fields...)
+		for _, field := range fields {
+			for _, fn := range this.hooks.fields {
+				if err := fn(table, field); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, fn := range this.hooks.afterAnalyse {
+		if err := fn(this); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove the column from the table together with the relations using
+// it. Indexes lose the column and are dropped when no column is left
+func (this *Generator) RemoveField(table *Table, name string) {
+	field := table.GetField(name)
+	if field == nil {
+		return
+	}
+	table.Fields = removeField(table.Fields, field)
+	table.Identity = removeField(table.Identity, field)
+	var indexes []*Index
+	for _, index := range table.Indexes {
+		index.Fields = removeField(index.Fields, field)
+		if len(index.Fields) > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	table.Indexes = indexes
+	for _, special := range []**Field{&table.Version, &table.SoftDelete, &table.Created, &table.Updated} {
+		if *special == field {
+			*special = nil
+		}
+	}
+	for _, other := range this.Tables {
+		var relations []*Relation
+		for _, rel := range other.Relations {
+			if rel.Column != field && rel.TargetColumn != field && rel.MiddleSrcColumn != field && rel.MiddleDstColumn != field {
+				relations = append(relations, rel)
+			}
+		}
+		other.Relations = relations
+	}
+}
+
+// list without the field
+func removeField(fields []*Field, field *Field) []*Field {
+	var rest []*Field
+	for _, f := range fields {
+		if f != field {
+			rest = append(rest, f)
+		}
+	}
+	return rest
+}
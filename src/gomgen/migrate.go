@@ -0,0 +1,399 @@
+package gomgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// changes turning the From schema into the To schema
+type SchemaDiff struct {
+	From, To      *Snapshot
+	AddedTables   []*SnapshotTable
+	RemovedTables []*SnapshotTable
+	ChangedTables []*TableDiff
+}
+
+// changes of a table present in both schemas
+type TableDiff struct {
+	From, To           *SnapshotTable
+	AddedColumns       []*SnapshotColumn
+	RemovedColumns     []*SnapshotColumn
+	ChangedColumns     []*ColumnChange
+	AddedIndexes       []*SnapshotIndex
+	RemovedIndexes     []*SnapshotIndex
+	AddedForeignKeys   []*SnapshotRelation
+	RemovedForeignKeys []*SnapshotRelation
+}
+
+// column with a different definition
+type ColumnChange struct {
+	From, To *SnapshotColumn
+}
+
+// compare two schema snapshots. Changed indexes and foreign keys are
+// reported as removed and added, as are the foreign keys of changed
+// columns, which MySQL does not modify while they are referenced
+func DiffSnapshots(from, to *Snapshot) *SchemaDiff {
+	diff := &SchemaDiff{From: from, To: to}
+	var tables []*TableDiff
+	for i := range to.Tables {
+		table := &to.Tables[i]
+		if old := from.table(table.Name); old == nil {
+			diff.AddedTables = append(diff.AddedTables, table)
+		} else {
+			tables = append(tables, diffTables(old, table))
+		}
+	}
+	changed := func(table, column string) bool {
+		for _, t := range tables {
+			if t.To.Name == table {
+				for _, change := range t.ChangedColumns {
+					if change.To.Name == column {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	for _, t := range tables {
+		for i := range t.To.Relations {
+			rel := &t.To.Relations[i]
+			if !changed(t.To.Name, rel.Column) && !changed(rel.Target, rel.TargetColumn) {
+				continue
+			}
+			if old := t.From.foreignKey(rel); old != nil && sameReference(old, rel) {
+				t.RemovedForeignKeys = append(t.RemovedForeignKeys, old)
+				t.AddedForeignKeys = append(t.AddedForeignKeys, rel)
+			}
+		}
+		if !t.Empty() {
+			diff.ChangedTables = append(diff.ChangedTables, t)
+		}
+	}
+	for i := range from.Tables {
+		if to.table(from.Tables[i].Name) == nil {
+			diff.RemovedTables = append(diff.RemovedTables, &from.Tables[i])
+		}
+	}
+	return diff
+}
+
+// compare the two states of the table
+func diffTables(from, to *SnapshotTable) *TableDiff {
+	diff := &TableDiff{From: from, To: to}
+
+	// columns
+	for i := range to.Columns {
+		column := &to.Columns[i]
+		if old := from.column(column.Name); old == nil {
+			diff.AddedColumns = append(diff.AddedColumns, column)
+		} else if columnSql(old) != columnSql(column) {
+			diff.ChangedColumns = append(diff.ChangedColumns, &ColumnChange{old, column})
+		}
+	}
+	for i := range from.Columns {
+		if to.column(from.Columns[i].Name) == nil {
+			diff.RemovedColumns = append(diff.RemovedColumns, &from.Columns[i])
+		}
+	}
+
+	// indexes
+	for i := range to.Indexes {
+		index := &to.Indexes[i]
+		if old := from.index(index.Name); old == nil || indexSql(old) != indexSql(index) {
+			diff.AddedIndexes = append(diff.AddedIndexes, index)
+		}
+	}
+	for i := range from.Indexes {
+		index := &from.Indexes[i]
+		if now := to.index(index.Name); now == nil || indexSql(now) != indexSql(index) {
+			diff.RemovedIndexes = append(diff.RemovedIndexes, index)
+		}
+	}
+
+	// foreign keys
+	for i := range to.Relations {
+		rel := &to.Relations[i]
+		if old := from.foreignKey(rel); old == nil || !sameReference(old, rel) {
+			diff.AddedForeignKeys = append(diff.AddedForeignKeys, rel)
+		}
+	}
+	for i := range from.Relations {
+		rel := &from.Relations[i]
+		if now := to.foreignKey(rel); now == nil || !sameReference(now, rel) {
+			diff.RemovedForeignKeys = append(diff.RemovedForeignKeys, rel)
+		}
+	}
+	return diff
+}
+
+// check if the schemas are the same
+func (this *SchemaDiff) Empty() bool {
+	return len(this.AddedTables) == 0 && len(this.RemovedTables) == 0 && len(this.ChangedTables) == 0
+}
+
+// check if the table is the same
+func (this *TableDiff) Empty() bool {
+	return len(this.AddedColumns) == 0 && len(this.RemovedColumns) == 0 && len(this.ChangedColumns) == 0 &&
+		len(this.AddedIndexes) == 0 && len(this.RemovedIndexes) == 0 &&
+		len(this.AddedForeignKeys) == 0 && len(this.RemovedForeignKeys) == 0
+}
+
+// readable report of the changes
+func (this *SchemaDiff) String() string {
+	var out strings.Builder
+	for _, table := range this.AddedTables {
+		fmt.Fprintf(&out, "+ table %s\n", table.Name)
+	}
+	for _, table := range this.RemovedTables {
+		fmt.Fprintf(&out, "- table %s\n", table.Name)
+	}
+	for _, table := range this.ChangedTables {
+		fmt.Fprintf(&out, "~ table %s\n", table.To.Name)
+		for _, column := range table.AddedColumns {
+			fmt.Fprintf(&out, "    + column %s\n", columnSql(column))
+		}
+		for _, column := range table.RemovedColumns {
+			fmt.Fprintf(&out, "    - column %s\n", columnSql(column))
+		}
+		for _, change := range table.ChangedColumns {
+			fmt.Fprintf(&out, "    ~ column %s -> %s\n", columnSql(change.From), columnSql(change.To))
+		}
+		for _, index := range table.AddedIndexes {
+			fmt.Fprintf(&out, "    + %s\n", indexSql(index))
+		}
+		for _, index := range table.RemovedIndexes {
+			fmt.Fprintf(&out, "    - %s\n", indexSql(index))
+		}
+		for _, rel := range table.AddedForeignKeys {
+			fmt.Fprintf(&out, "    + %s\n", foreignKeySql(rel))
+		}
+		for _, rel := range table.RemovedForeignKeys {
+			fmt.Fprintf(&out, "    - %s\n", foreignKeySql(rel))
+		}
+	}
+	return out.String()
+}
+
+// statements migrating the From schema to the To schema. Foreign keys
+// are dropped first and added last so the order of the tables does
+// not matter
+func (this *SchemaDiff) Up() []string {
+	var stmts []string
+
+	// foreign keys going away
+	for _, table := range this.ChangedTables {
+		for _, rel := range table.RemovedForeignKeys {
+			stmts = append(stmts, "ALTER TABLE "+quoteName(table.From.Name)+" DROP FOREIGN KEY "+quoteName(rel.Constraint)+";")
+		}
+	}
+	for _, table := range this.RemovedTables {
+		for _, rel := range table.Relations {
+			stmts = append(stmts, "ALTER TABLE "+quoteName(table.Name)+" DROP FOREIGN KEY "+quoteName(rel.Constraint)+";")
+		}
+	}
+
+	// tables
+	for _, table := range this.RemovedTables {
+		stmts = append(stmts, "DROP TABLE "+quoteName(table.Name)+";")
+	}
+	for _, table := range this.AddedTables {
+		stmts = append(stmts, createTableSql(table, false))
+	}
+
+	// columns and indexes
+	for _, table := range this.ChangedTables {
+		var clauses []string
+		for _, index := range table.RemovedIndexes {
+			if index.Name == "PRIMARY" {
+				clauses = append(clauses, "DROP PRIMARY KEY")
+			} else {
+				clauses = append(clauses, "DROP INDEX "+quoteName(index.Name))
+			}
+		}
+		for _, column := range table.RemovedColumns {
+			clauses = append(clauses, "DROP COLUMN "+quoteName(column.Name))
+		}
+		for _, column := range table.AddedColumns {
+			clauses = append(clauses, "ADD COLUMN "+columnSql(column)+" "+table.To.position(column.Name))
+		}
+		for _, change := range table.ChangedColumns {
+			clauses = append(clauses, "MODIFY COLUMN "+columnSql(change.To))
+		}
+		for _, index := range table.AddedIndexes {
+			clauses = append(clauses, "ADD "+indexSql(index))
+		}
+		if len(clauses) > 0 {
+			stmts = append(stmts, "ALTER TABLE "+quoteName(table.To.Name)+"\n  "+strings.Join(clauses, ",\n  ")+";")
+		}
+	}
+
+	// new foreign keys
+	for _, table := range this.AddedTables {
+		for i := range table.Relations {
+			stmts = append(stmts, "ALTER TABLE "+quoteName(table.Name)+" ADD "+foreignKeySql(&table.Relations[i])+";")
+		}
+	}
+	for _, table := range this.ChangedTables {
+		for _, rel := range table.AddedForeignKeys {
+			stmts = append(stmts, "ALTER TABLE "+quoteName(table.To.Name)+" ADD "+foreignKeySql(rel)+";")
+		}
+	}
+	return stmts
+}
+
+// statements reverting Up
+func (this *SchemaDiff) Down() []string {
+	return DiffSnapshots(this.To, this.From).Up()
+}
+
+// CREATE TABLE statement. Foreign keys are optional so tables can be
+// created in any order and linked afterwards
+func createTableSql(table *SnapshotTable, foreignKeys bool) string {
+	var lines []string
+	for i := range table.Columns {
+		lines = append(lines, columnSql(&table.Columns[i]))
+	}
+	for i := range table.Indexes {
+		lines = append(lines, indexSql(&table.Indexes[i]))
+	}
+	if foreignKeys {
+		for i := range table.Relations {
+			lines = append(lines, foreignKeySql(&table.Relations[i]))
+		}
+	}
+	sql := "CREATE TABLE " + quoteName(table.Name) + " (\n  " + strings.Join(lines, ",\n  ") + "\n)"
+	if table.Comment != "" {
+		sql += " COMMENT " + quoteString(table.Comment)
+	}
+	return sql + ";"
+}
+
+// numbers and expressions are not quoted as default values
+var sqlRawDefault = regexp.MustCompile(`^(-?[0-9]+(\.[0-9]+)?|CURRENT_TIMESTAMP(\([0-9]*\))?|\(.*\))$`)
+
+// column definition
+func columnSql(column *SnapshotColumn) string {
+	sql := quoteName(column.Name) + " " + column.Type
+	if column.Nullable {
+		sql += " NULL"
+	} else {
+		sql += " NOT NULL"
+	}
+	if column.Default != nil {
+		if sqlRawDefault.MatchString(*column.Default) {
+			sql += " DEFAULT " + *column.Default
+		} else {
+			sql += " DEFAULT " + quoteString(*column.Default)
+		}
+	} else if column.Nullable {
+		sql += " DEFAULT NULL"
+	}
+	if extra := strings.TrimSpace(strings.Replace(column.Extra, "DEFAULT_GENERATED", "", 1)); extra != "" {
+		sql += " " + strings.ToUpper(extra)
+	}
+	if column.Comment != "" {
+		sql += " COMMENT " + quoteString(column.Comment)
+	}
+	return sql
+}
+
+// index definition
+func indexSql(index *SnapshotIndex) string {
+	var columns []string
+	for _, column := range index.Columns {
+		columns = append(columns, quoteName(column))
+	}
+	switch {
+	case index.Name == "PRIMARY":
+		return "PRIMARY KEY (" + strings.Join(columns, ", ") + ")"
+	case index.Unique:
+		return "UNIQUE KEY " + quoteName(index.Name) + " (" + strings.Join(columns, ", ") + ")"
+	}
+	return "KEY " + quoteName(index.Name) + " (" + strings.Join(columns, ", ") + ")"
+}
+
+// foreign key definition
+func foreignKeySql(rel *SnapshotRelation) string {
+	sql := "FOREIGN KEY (" + quoteName(rel.Column) + ") REFERENCES " + quoteName(rel.Target) + " (" + quoteName(rel.TargetColumn) + ")"
+	if rel.Constraint != "" {
+		sql = "CONSTRAINT " + quoteName(rel.Constraint) + " " + sql
+	}
+	return sql
+}
+
+// check if the foreign keys reference the same column
+func sameReference(a, b *SnapshotRelation) bool {
+	return a.Column == b.Column && a.Target == b.Target && a.TargetColumn == b.TargetColumn
+}
+
+// escape identifier
+func quoteName(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// escape string literal
+func quoteString(value string) string {
+	return "'" + strings.Replace(strings.Replace(value, `\`, `\\`, -1), "'", "''", -1) + "'"
+}
+
+// find the table by name
+func (this *Snapshot) table(name string) *SnapshotTable {
+	for i := range this.Tables {
+		if this.Tables[i].Name == name {
+			return &this.Tables[i]
+		}
+	}
+	return nil
+}
+
+// find the column by name
+func (this *SnapshotTable) column(name string) *SnapshotColumn {
+	for i := range this.Columns {
+		if this.Columns[i].Name == name {
+			return &this.Columns[i]
+		}
+	}
+	return nil
+}
+
+// find the index by name
+func (this *SnapshotTable) index(name string) *SnapshotIndex {
+	for i := range this.Indexes {
+		if this.Indexes[i].Name == name {
+			return &this.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// find the foreign key by constraint name, or by column when the
+// constraint name is unknown
+func (this *SnapshotTable) foreignKey(rel *SnapshotRelation) *SnapshotRelation {
+	for i := range this.Relations {
+		other := &this.Relations[i]
+		if rel.Constraint == "" || other.Constraint == "" {
+			if other.Column == rel.Column {
+				return other
+			}
+		} else if other.Constraint == rel.Constraint {
+			return other
+		}
+	}
+	return nil
+}
+
+// placement of the added column after its predecessor
+func (this *SnapshotTable) position(name string) string {
+	for i := range this.Columns {
+		if this.Columns[i].Name == name {
+			if i == 0 {
+				return "FIRST"
+			}
+			return "AFTER " + quoteName(this.Columns[i-1].Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package gomgen
+
+import (
+	"reflect"
+	"testing"
+)
+
+// not null int column
+func intColumn(name string) SnapshotColumn {
+	return SnapshotColumn{Name: name, Type: "int(11)"}
+}
+
+// table with an int primary key and the columns
+func snapshotTableOf(name string, columns ...string) SnapshotTable {
+	table := SnapshotTable{
+		Name:     name,
+		Columns:  []SnapshotColumn{{Name: "id", Type: "int(11)", Key: "PRI", Extra: "auto_increment"}},
+		Identity: []string{"id"},
+		Indexes:  []SnapshotIndex{{Name: "PRIMARY", Unique: true, Columns: []string{"id"}}},
+	}
+	for _, column := range columns {
+		table.Columns = append(table.Columns, intColumn(column))
+	}
+	return table
+}
+
+func TestSchemaDiff(t *testing.T) {
+	author := snapshotTableOf("author")
+	article := snapshotTableOf("article", "author_id")
+	article.Relations = []SnapshotRelation{{Name: "Author", Constraint: "fk_article_author", Column: "author_id", Target: "author", TargetColumn: "id"}}
+
+	tests := []struct {
+		name     string
+		from, to []SnapshotTable
+		up, down []string
+	}{
+		{
+			name: "identical",
+			from: []SnapshotTable{author, article},
+			to:   []SnapshotTable{author, article},
+		},
+		{
+			name: "added tables linked by a foreign key",
+			from: nil,
+			to:   []SnapshotTable{article, author},
+			up: []string{
+				"CREATE TABLE `article` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n  `author_id` int(11) NOT NULL,\n  PRIMARY KEY (`id`)\n);",
+				"CREATE TABLE `author` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n);",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`);",
+			},
+			down: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"DROP TABLE `article`;",
+				"DROP TABLE `author`;",
+			},
+		},
+		{
+			name: "columns added first and after a column, one dropped",
+			from: []SnapshotTable{snapshotTableOf("tag", "a", "b", "c")},
+			to: []SnapshotTable{func() SnapshotTable {
+				table := snapshotTableOf("tag", "a", "x", "c")
+				table.Columns = append([]SnapshotColumn{intColumn("first")}, table.Columns...)
+				return table
+			}()},
+			up: []string{
+				"ALTER TABLE `tag`\n  DROP COLUMN `b`,\n  ADD COLUMN `first` int(11) NOT NULL FIRST,\n  ADD COLUMN `x` int(11) NOT NULL AFTER `a`;",
+			},
+			down: []string{
+				"ALTER TABLE `tag`\n  DROP COLUMN `first`,\n  DROP COLUMN `x`,\n  ADD COLUMN `b` int(11) NOT NULL AFTER `a`;",
+			},
+		},
+		{
+			name: "changed column and index",
+			from: []SnapshotTable{snapshotTableOf("tag", "a")},
+			to: []SnapshotTable{func() SnapshotTable {
+				table := snapshotTableOf("tag", "a")
+				table.Columns[1].Type = "bigint(20)"
+				table.Indexes = append(table.Indexes, SnapshotIndex{Name: "uq_a", Unique: true, Columns: []string{"a"}})
+				return table
+			}()},
+			up: []string{
+				"ALTER TABLE `tag`\n  MODIFY COLUMN `a` bigint(20) NOT NULL,\n  ADD UNIQUE KEY `uq_a` (`a`);",
+			},
+			down: []string{
+				"ALTER TABLE `tag`\n  DROP INDEX `uq_a`,\n  MODIFY COLUMN `a` int(11) NOT NULL;",
+			},
+		},
+		{
+			name: "columns of a foreign key change type",
+			from: []SnapshotTable{author, article},
+			to: func() []SnapshotTable {
+				author, article := snapshotTableOf("author"), snapshotTableOf("article", "author_id")
+				author.Columns[0].Type = "bigint(20)"
+				article.Columns[1].Type = "bigint(20)"
+				article.Relations = []SnapshotRelation{{Name: "Author", Constraint: "fk_article_author", Column: "author_id", Target: "author", TargetColumn: "id"}}
+				return []SnapshotTable{author, article}
+			}(),
+			up: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"ALTER TABLE `author`\n  MODIFY COLUMN `id` bigint(20) NOT NULL AUTO_INCREMENT;",
+				"ALTER TABLE `article`\n  MODIFY COLUMN `author_id` bigint(20) NOT NULL;",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`);",
+			},
+			down: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"ALTER TABLE `author`\n  MODIFY COLUMN `id` int(11) NOT NULL AUTO_INCREMENT;",
+				"ALTER TABLE `article`\n  MODIFY COLUMN `author_id` int(11) NOT NULL;",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`);",
+			},
+		},
+		{
+			name: "referenced column changes type",
+			from: []SnapshotTable{author, article},
+			to: func() []SnapshotTable {
+				author := snapshotTableOf("author")
+				author.Columns[0].Comment = "key"
+				return []SnapshotTable{author, article}
+			}(),
+			up: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"ALTER TABLE `author`\n  MODIFY COLUMN `id` int(11) NOT NULL AUTO_INCREMENT COMMENT 'key';",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`);",
+			},
+			down: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"ALTER TABLE `author`\n  MODIFY COLUMN `id` int(11) NOT NULL AUTO_INCREMENT;",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`);",
+			},
+		},
+		{
+			name: "foreign key moved to another column",
+			from: []SnapshotTable{author, article},
+			to: []SnapshotTable{author, func() SnapshotTable {
+				table := snapshotTableOf("article", "author_id", "editor_id")
+				table.Relations = []SnapshotRelation{{Name: "Author", Constraint: "fk_article_author", Column: "editor_id", Target: "author", TargetColumn: "id"}}
+				return table
+			}()},
+			up: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"ALTER TABLE `article`\n  ADD COLUMN `editor_id` int(11) NOT NULL AFTER `author_id`;",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`editor_id`) REFERENCES `author` (`id`);",
+			},
+			down: []string{
+				"ALTER TABLE `article` DROP FOREIGN KEY `fk_article_author`;",
+				"ALTER TABLE `article`\n  DROP COLUMN `editor_id`;",
+				"ALTER TABLE `article` ADD CONSTRAINT `fk_article_author` FOREIGN KEY (`author_id`) REFERENCES `author` (`id`);",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from := &Snapshot{Version: SnapshotVersion, Tables: test.from}
+			to := &Snapshot{Version: SnapshotVersion, Tables: test.to}
+			diff := DiffSnapshots(from, to)
+			if diff.Empty() != (test.up == nil) {
+				t.Errorf("Empty() = %v", diff.Empty())
+			}
+			if up := diff.Up(); !reflect.DeepEqual(up, test.up) {
+				t.Errorf("Up()\ngot  %q\nwant %q", up, test.up)
+			}
+			if down := diff.Down(); !reflect.DeepEqual(down, test.down) {
+				t.Errorf("Down()\ngot  %q\nwant %q", down, test.down)
+			}
+
+			// down is the up of the reverse diff
+			if down, reverse := diff.Down(), DiffSnapshots(to, from).Up(); !reflect.DeepEqual(down, reverse) {
+				t.Errorf("Down() differs from the reverse Up()\ngot  %q\nwant %q", down, reverse)
+			}
+		})
+	}
+}
+
+func TestSchemaDiffIgnoresUnnamedConstraints(t *testing.T) {
+	named := snapshotTableOf("article", "author_id")
+	named.Relations = []SnapshotRelation{{Constraint: "fk_article_author", Column: "author_id", Target: "author", TargetColumn: "id"}}
+	unnamed := snapshotTableOf("article", "author_id")
+	unnamed.Relations = []SnapshotRelation{{Column: "author_id", Target: "author", TargetColumn: "id"}}
+	author := snapshotTableOf("author")
+
+	from := &Snapshot{Tables: []SnapshotTable{author, unnamed}}
+	to := &Snapshot{Tables: []SnapshotTable{author, named}}
+	if diff := DiffSnapshots(from, to); !diff.Empty() {
+		t.Errorf("snapshot without constraint names differs:\n%s", diff)
+	}
+}
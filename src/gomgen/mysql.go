@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"regexp"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // mysql analyzer
@@ -28,6 +30,13 @@ func (this *Mysql) Analyze(gen *Generator) error {
 		}
 	}
 
+	// fetch the indexes
+	for _, table := range this.gen.Tables {
+		if err := this.fetchIndexes(table); err != nil {
+			return err
+		}
+	}
+
 	// fetch the references
 	for _, table := range this.gen.Tables {
 		if err := this.fetchRelations(table); err != nil {
@@ -108,19 +117,34 @@ func (this *Mysql) fetchRelations(table *Table) error {
 		fmt.Printf("%v: %v -> %v.%v\n", name, srcColumn, dstTable, dstColumn)
 
 		// relation name
+		constraint := name
 		t := sqlTableIdFieldMatch.FindStringSubmatch(srcColumn)
 		if len(t) > 1 {
 			name = t[1]
 		}
 
 		// add relation to the source
-		srcRelation := NewRelation(name)
-		srcRelation.Table = table
-		srcRelation.Column = table.GetField(srcColumn)
-		srcRelation.TargetEntity = this.gen.GetTable(dstTable)
-		srcRelation.TargetColumn = srcRelation.TargetEntity.GetField(dstColumn)
-		table.Relations = append(table.Relations, srcRelation)
+		if err := this.addRelation(table, name, constraint, srcColumn, dstTable, dstColumn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add the foreign key of the table as a relation
+func (this *Mysql) addRelation(table *Table, name, constraint, srcColumn, dstTable, dstColumn string) error {
+	target := this.gen.GetTable(dstTable)
+	if target == nil {
+		return fmt.Errorf("%s.%s references unknown table %s", table.Name, srcColumn, dstTable)
 	}
+	srcRelation := NewRelation(name)
+	srcRelation.Type = OneToOne
+	srcRelation.Constraint = constraint
+	srcRelation.Table = table
+	srcRelation.Column = table.GetField(srcColumn)
+	srcRelation.TargetEntity = target
+	srcRelation.TargetColumn = target.GetField(dstColumn)
+	table.Relations = append(table.Relations, srcRelation)
 	return nil
 }
 
@@ -156,37 +180,159 @@ func (this *Mysql) fetchColumns(table *Table) error {
 		}
 
 		// add field to the table
-		field := NewField(name)
-		field.EscapedName = "`" + name + "`"
-		field.Default = def
-		field.Nullable = nullable == "YES"
-		field.Comment = comment
-		field.Type = this.detetcType(typ, field.Nullable)
-		field.GoType = GoTypeMap[field.Type]
-		field.Primary = key == "PRI"
-		field.AutoInc = field.Primary && extra == "auto_increment"
-		field.Comment = comment
+		this.addField(table, name, def, nullable == "YES", typ, key, extra, comment)
+	}
+
+	// done
+	return nil
+}
 
-		// add to table identity
-		if field.Primary {
-			table.Identity = append(table.Identity, field)
+// add the column to the table. Key and extra are the COLUMN_KEY and
+// EXTRA of the information_schema
+func (this *Mysql) addField(table *Table, name string, def sql.NullString, nullable bool, typ, key, extra, comment string) *Field {
+	field := NewField(name)
+	field.EscapedName = "`" + name + "`"
+	field.Default = def
+	field.Nullable = nullable
+	field.Comment = comment
+	field.SqlType = typ
+	field.Key = key
+	field.Extra = extra
+	this.parseType(field, typ)
+	field.Type = this.detetcType(field.BaseType, field.Length, field.Nullable)
+	field.GoType = GoTypeMap[field.Type]
+	field.Primary = key == "PRI"
+	field.AutoInc = field.Primary && extra == "auto_increment"
+
+	// add to table identity
+	if field.Primary {
+		table.Identity = append(table.Identity, field)
+	}
+
+	// need to import time?
+	if field.Type == GoTime || field.Type == GoNullTime {
+		this.gen.Imports["time"] = true
+		field.Format = sqlTimeFormats[field.BaseType]
+	}
+
+	table.Fields = append(table.Fields, field)
+	return field
+}
+
+// fetch table indexes
+func (this *Mysql) fetchIndexes(table *Table) error {
+	SQL := `
+		SELECT		Stats.INDEX_NAME,
+					Stats.COLUMN_NAME,
+					Stats.NON_UNIQUE,
+					Stats.SEQ_IN_INDEX
+		FROM		information_schema.STATISTICS AS Stats
+		WHERE		Stats.TABLE_SCHEMA = ? AND Stats.TABLE_NAME = ?
+		ORDER BY	Stats.INDEX_NAME, Stats.SEQ_IN_INDEX
+	`
+	rows, err := this.gen.Db.Query(SQL, this.gen.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// process rows
+	for rows.Next() {
+		var name, column string
+		var nonUnique, seq int
+		if err := rows.Scan(&name, &column, &nonUnique, &seq); err != nil {
+			return err
 		}
+		this.addIndex(table, name, column, nonUnique == 0)
+	}
+	return nil
+}
 
-		// need to import time?
-		if field.Type == GoTime {
-			this.gen.Imports["time"] = true
-			field.Format = sqlTimeFormats[typ]
+// add the column to the index of the table, creating the index when needed
+func (this *Mysql) addIndex(table *Table, name, column string, unique bool) {
+	var index *Index
+	for _, idx := range table.Indexes {
+		if idx.Name == name {
+			index = idx
 		}
+	}
+	if index == nil {
+		index = &Index{Name: name, Unique: unique, Primary: name == "PRIMARY"}
+		table.Indexes = append(table.Indexes, index)
+	}
+	if field := table.GetField(column); field != nil {
+		index.Fields = append(index.Fields, field)
+	}
+}
+
+// use this to decode sql types. int(11), decimal(10,2), enum('a','b'), int(10) unsigned ...
+var sqlTypeMatch = regexp.MustCompile(`^([a-zA-Z_]+)(?:\((.*)\))?(.*)$`)
 
-		table.Fields = append(table.Fields, field)
+// store the constraints declared by the column type on the field
+func (this *Mysql) parseType(field *Field, sqlType string) {
+	t := sqlTypeMatch.FindStringSubmatch(sqlType)
+	if len(t) == 0 {
+		field.BaseType = sqlType
+		return
+	}
+	field.BaseType = strings.ToLower(t[1])
+	field.Unsigned = strings.Contains(t[3], "unsigned")
+	switch field.BaseType {
+	case "enum", "set":
+		field.Values = parseSqlValues(t[2])
+	default:
+		// size or precision. Scale is not used
+		size := strings.SplitN(t[2], ",", 2)[0]
+		length, _ := strconv.ParseInt(size, 10, 32)
+		field.Length = int(length)
 	}
 
-	// done
-	return nil
+	// integer ranges
+	if r, ok := sqlIntRanges[field.BaseType]; ok {
+		field.HasRange = true
+		if field.Unsigned {
+			field.Min, field.Max = 0, r[1]-r[0]
+		} else {
+			field.Min, field.Max = r[0], r[1]
+		}
+	} else if field.BaseType == "bigint" && field.Unsigned {
+		field.HasRange = true
+		field.Min, field.Max = 0, math.MaxInt64
+	}
 }
 
-// use this to decode sql types. int(11), ...
-var sqlTypeMatch = regexp.MustCompile(`^([a-zA-Z_]+)\(([0-9]+)(,[0-9]+)?\)$`)
+// signed ranges of the integer types that fit into int64
+var sqlIntRanges = map[string][2]int64{
+	"tinyint":   {math.MinInt8, math.MaxInt8},
+	"smallint":  {math.MinInt16, math.MaxInt16},
+	"mediumint": {-1 << 23, 1<<23 - 1},
+	"int":       {math.MinInt32, math.MaxInt32},
+	"integer":   {math.MinInt32, math.MaxInt32},
+}
+
+// parse quoted list of enum values. 'a','b''c' -> a, b'c
+func parseSqlValues(list string) []string {
+	var values []string
+	var value []byte
+	quoted := false
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(list) && list[i+1] == '\'':
+			value = append(value, c)
+			i++
+		case c == '\'':
+			quoted = !quoted
+			if !quoted {
+				values = append(values, string(value))
+				value = value[:0]
+			}
+		case quoted:
+			value = append(value, c)
+		}
+	}
+	return values
+}
 
 // sql time formats
 var sqlTimeFormats = map[string]string{
@@ -196,18 +342,10 @@ var sqlTimeFormats = map[string]string{
 }
 
 // convert sql data type to go type
-func (this *Mysql) detetcType(sqlType string, nullable bool) GoType {
-
-	t := sqlTypeMatch.FindStringSubmatch(sqlType)
-	size := int64(-1)
-	if len(t) > 0 {
-		sqlType = t[1]
-		size, _ = strconv.ParseInt(t[2], 10, 32)
-	}
-
+func (this *Mysql) detetcType(sqlType string, size int, nullable bool) GoType {
 	switch sqlType {
-	case "int", "smallint", "tinyint", "bool":
-		if size == 1 || sqlType == "bool" {
+	case "int", "integer", "bigint", "mediumint", "smallint", "tinyint", "bool", "boolean":
+		if size == 1 || sqlType == "bool" || sqlType == "boolean" {
 			if nullable {
 				return GoNullBool
 			}
@@ -227,14 +365,14 @@ func (this *Mysql) detetcType(sqlType string, nullable bool) GoType {
 			return GoNullFloat64
 		}
 		return GoFloat64
-	case "text", "enum", "set":
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set":
 		if nullable {
 			return GoNullString
 		}
 		return GoString
 	case "datetime", "time", "date":
 		if nullable {
-			panic("Nuulable datetime not implemented yet")
+			return GoNullTime
 		}
 		return GoTime
 	}
@@ -242,3 +380,20 @@ func (this *Mysql) detetcType(sqlType string, nullable bool) GoType {
 	// default to string
 	return GoString
 }
+
+// CREATE TABLE statement of the table. The primary key comes from the
+// identity when the indexes are not known
+func (this *Mysql) CreateTable(table *Table) string {
+	t := snapshotTable(table)
+	if t.index("PRIMARY") == nil && len(t.Identity) > 0 {
+		primary := SnapshotIndex{Name: "PRIMARY", Unique: true, Columns: t.Identity}
+		t.Indexes = append([]SnapshotIndex{primary}, t.Indexes...)
+	}
+	return createTableSql(&t, false)
+}
+
+// ALTER TABLE statement adding the foreign key
+func (this *Mysql) AddForeignKey(rel *Relation) string {
+	fk := snapshotRelation(rel)
+	return "ALTER TABLE " + quoteName(rel.Table.Name) + " ADD " + foreignKeySql(&fk) + ";"
+}
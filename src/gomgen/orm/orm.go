@@ -0,0 +1,106 @@
+// Runtime of the models generated by gomgen. The generated entities
+// describe themselves with a Meta and the generic functions do the
+// querying, scanning and inserting
+package orm
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// object can be scanned. Row, Rows
+type Scanner interface {
+	Scan(...interface{}) error
+}
+
+// runs select queries. The database or a transaction
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// runs statements. The database or a transaction
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// description of the entity T and its table
+type Meta[T any] struct {
+	Table    string   // escaped table name
+	Columns  []string // escaped columns in the scan order
+	Identity []string // escaped primary key columns
+	Inserted []string // escaped columns written by Insert
+
+	// scan the row into the entity
+	Scan func(entity *T, row Scanner) error
+
+	// values of the Inserted columns
+	Values func(entity *T) []interface{}
+
+	// assign the auto increment id. Nil without auto increment column
+	SetId func(entity *T, id int64)
+}
+
+// select statement of the columns from the table. From replaces the
+// table in the FROM clause, for example with a derived table
+func (this *Meta[T]) Select(from string) string {
+	if from == "" {
+		from = this.Table
+	}
+	cols := make([]string, len(this.Columns))
+	for i, col := range this.Columns {
+		cols[i] = this.Table + "." + col
+	}
+	return "SELECT " + strings.Join(cols, ", ") + " FROM " + from
+}
+
+// insert statement of the Inserted columns
+func (this *Meta[T]) InsertSql() string {
+	vals := strings.TrimSuffix(strings.Repeat("?, ", len(this.Inserted)), ", ")
+	return "INSERT INTO " + this.Table + " (" + strings.Join(this.Inserted, ", ") + ") VALUES (" + vals + ")"
+}
+
+// run the select query and scan the first row. Returns sql.ErrNoRows
+// when nothing is found
+func Find[T any](db Querier, meta *Meta[T], query string, args ...interface{}) (*T, error) {
+	entity := new(T)
+	if err := meta.Scan(entity, db.QueryRow(query, args...)); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// run the select query and scan all rows
+func All[T any](db Querier, meta *Meta[T], query string, args ...interface{}) ([]*T, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entities []*T
+	for rows.Next() {
+		entity := new(T)
+		if err := meta.Scan(entity, rows); err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	return entities, rows.Err()
+}
+
+// insert the entity as a new row and assign the auto increment id
+func Insert[T any](db Executor, meta *Meta[T], entity *T) error {
+	result, err := db.Exec(meta.InsertSql(), meta.Values(entity)...)
+	if err != nil {
+		return err
+	}
+	if meta.SetId == nil {
+		return nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	meta.SetId(entity, id)
+	return nil
+}
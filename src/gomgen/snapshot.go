@@ -0,0 +1,220 @@
+package gomgen
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// version of the snapshot format written by SaveSnapshot
+const SnapshotVersion = 1
+
+// tables as found by the Analyzer, before the relations are linked
+// and the conventions applied. Saved as json it makes the generation
+// reproducible without the database
+type Snapshot struct {
+	Version int             `json:"version"`
+	Schema  string          `json:"schema"`
+	Tables  []SnapshotTable `json:"tables"`
+}
+
+// table of the snapshot
+type SnapshotTable struct {
+	Name      string             `json:"name"`
+	Comment   string             `json:"comment,omitempty"`
+	Columns   []SnapshotColumn   `json:"columns"`
+	Identity  []string           `json:"identity,omitempty"`
+	Relations []SnapshotRelation `json:"relations,omitempty"`
+	Indexes   []SnapshotIndex    `json:"indexes,omitempty"`
+}
+
+// column of the snapshot table
+type SnapshotColumn struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default"`
+	Key      string  `json:"key,omitempty"`
+	Extra    string  `json:"extra,omitempty"`
+	Comment  string  `json:"comment,omitempty"`
+}
+
+// foreign key of the snapshot table
+type SnapshotRelation struct {
+	Name         string `json:"name"`
+	Constraint   string `json:"constraint"`
+	Column       string `json:"column"`
+	Target       string `json:"target"`
+	TargetColumn string `json:"target_column"`
+}
+
+// index of the snapshot table
+type SnapshotIndex struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+}
+
+// take snapshot of the analysed tables
+func NewSnapshot(gen *Generator) *Snapshot {
+	snapshot := &Snapshot{Version: SnapshotVersion, Schema: gen.Schema}
+	for _, table := range gen.Tables {
+		snapshot.Tables = append(snapshot.Tables, snapshotTable(table))
+	}
+	return snapshot
+}
+
+// snapshot of the table
+func snapshotTable(table *Table) SnapshotTable {
+	t := SnapshotTable{Name: table.Name, Comment: table.Comment}
+	for _, field := range table.Fields {
+		column := SnapshotColumn{
+			Name:     field.RealName,
+			Type:     field.SqlType,
+			Nullable: field.Nullable,
+			Key:      field.Key,
+			Extra:    field.Extra,
+			Comment:  field.Comment,
+		}
+		if field.Default.Valid {
+			def := field.Default.String
+			column.Default = &def
+		}
+		t.Columns = append(t.Columns, column)
+	}
+	for _, field := range table.Identity {
+		t.Identity = append(t.Identity, field.RealName)
+	}
+	for _, rel := range table.Relations {
+		if rel.Type == OneToOne {
+			t.Relations = append(t.Relations, snapshotRelation(rel))
+		}
+	}
+	for _, index := range table.Indexes {
+		i := SnapshotIndex{Name: index.Name, Unique: index.Unique}
+		for _, field := range index.Fields {
+			i.Columns = append(i.Columns, field.RealName)
+		}
+		t.Indexes = append(t.Indexes, i)
+	}
+	return t
+}
+
+// snapshot of the foreign key
+func snapshotRelation(rel *Relation) SnapshotRelation {
+	return SnapshotRelation{
+		Name:         rel.Name,
+		Constraint:   rel.Constraint,
+		Column:       rel.Column.RealName,
+		Target:       rel.TargetEntity.Name,
+		TargetColumn: rel.TargetColumn.RealName,
+	}
+}
+
+// write the snapshot of the last Analyse into the json file
+func (this *Generator) SaveSnapshot(path string) error {
+	if this.Snapshot == nil {
+		return fmt.Errorf("no snapshot, call Analyse first")
+	}
+	data, err := json.MarshalIndent(this.Snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// read the snapshot from the json file
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d", path, snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// analyzer reading the tables from a snapshot file instead of the database
+type SnapshotAnalyzer struct {
+	Path string
+}
+
+// load the tables from the snapshot
+func (this *SnapshotAnalyzer) Analyze(gen *Generator) error {
+	snapshot, err := LoadSnapshot(this.Path)
+	if err != nil {
+		return err
+	}
+	if gen.Schema == "" {
+		gen.Schema = snapshot.Schema
+	}
+
+	// columns are decoded the same way as from the database
+	mysql := &Mysql{gen: gen}
+	for _, t := range snapshot.Tables {
+		table := NewTable(t.Name, t.Comment)
+		table.EscapedName = "`" + t.Name + "`"
+		for _, column := range t.Columns {
+			var def sql.NullString
+			if column.Default != nil {
+				def = sql.NullString{String: *column.Default, Valid: true}
+			}
+			mysql.addField(table, column.Name, def, column.Nullable, column.Type, column.Key, column.Extra, column.Comment)
+		}
+		for _, index := range t.Indexes {
+			for _, column := range index.Columns {
+				if _, err := this.field(table, column); err != nil {
+					return err
+				}
+				mysql.addIndex(table, index.Name, column, index.Unique)
+			}
+		}
+		if len(t.Identity) > 0 {
+			table.Identity = nil
+			for _, column := range t.Identity {
+				field, err := this.field(table, column)
+				if err != nil {
+					return err
+				}
+				table.Identity = append(table.Identity, field)
+			}
+		}
+		gen.Tables = append(gen.Tables, table)
+	}
+
+	// relations once all the tables exist
+	for i, t := range snapshot.Tables {
+		for _, rel := range t.Relations {
+			table, target := gen.Tables[i], gen.GetTable(rel.Target)
+			if target == nil {
+				return fmt.Errorf("%s: table %s references unknown table %s", this.Path, t.Name, rel.Target)
+			}
+			if _, err := this.field(table, rel.Column); err != nil {
+				return err
+			}
+			if _, err := this.field(target, rel.TargetColumn); err != nil {
+				return err
+			}
+			if err := mysql.addRelation(table, rel.Name, rel.Constraint, rel.Column, rel.Target, rel.TargetColumn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// find the column named in the snapshot. Snapshots are edited by hand,
+// so the names are checked
+func (this *SnapshotAnalyzer) field(table *Table, column string) (*Field, error) {
+	field := table.GetField(column)
+	if field == nil {
+		return nil, fmt.Errorf("%s: table %s has no column %s", this.Path, table.Name, column)
+	}
+	return field, nil
+}
@@ -0,0 +1,89 @@
+package gomgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// build struct tags of the table fields from the tag config
+func (this *Generator) buildTags(table *Table) error {
+	config := this.Config.Tags
+
+	// parse custom tag templates
+	var names []string
+	custom := make(map[string]*template.Template)
+	for name, text := range config.Custom {
+		t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return fmt.Errorf("tag %s: %v", name, err)
+		}
+		custom[name] = t
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, field := range table.Fields {
+		values := make(map[string]string)
+		order := []string{"db", "json"}
+
+		if config.Db {
+			values["db"] = field.RealName
+		}
+		if key := jsonKey(field.RealName, config.Json); key != "" {
+			if field.Nullable && config.OmitEmpty {
+				key += ",omitempty"
+			}
+			values["json"] = key
+		}
+		for _, name := range names {
+			var value bytes.Buffer
+			if err := custom[name].Execute(&value, field); err != nil {
+				return fmt.Errorf("tag %s: %v", name, err)
+			}
+			values[name] = value.String()
+			order = append(order, name)
+		}
+
+		// column overrides
+		overrides := config.Override[table.Name+"."+field.RealName]
+		var extra []string
+		for name, value := range overrides {
+			if _, ok := values[name]; !ok {
+				extra = append(extra, name)
+			}
+			values[name] = value
+		}
+		sort.Strings(extra)
+		order = append(order, extra...)
+
+		// render
+		var tags []string
+		for _, name := range order {
+			if value := values[name]; value != "" {
+				tags = append(tags, name+":"+strconv.Quote(value))
+			}
+		}
+		field.Tag = strings.Join(tags, " ")
+		field.JsonTag = values["json"]
+	}
+	return nil
+}
+
+// json key of the column in the naming style
+func jsonKey(column, style string) string {
+	switch style {
+	case "snake":
+		return strings.ToLower(column)
+	case "camel":
+		parts := strings.Split(strings.ToLower(column), "_")
+		for i := 1; i < len(parts); i++ {
+			parts[i] = strings.Title(parts[i])
+		}
+		return strings.Join(parts, "")
+	}
+	return ""
+}
@@ -1,150 +1,113 @@
 package gomgen
 
-/*********************************************************
- * File header, imports and db part
- *********************************************************/
-const headerTpl = `// Autogenerated by gomgen
-package model
-
 import (
-	{{range $k, $v := .Imports}}"{{ $k }}"
-	{{end}}
+	"bitbucket.org/pkg/inflect"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
 )
 
-// object can be scanned. Row, Rows
-type scannable interface {
-	Scan(...interface{}) error
-}
-
-// database connnection
-var theDb *sql.DB
-
-// register db object for the models
-func Register(db *sql.DB) error {
-	theDb = db
-	return nil
-}
-`
-
-/*********************************************************
- * Generate entity struct type with the fields
- *********************************************************/
-const entityStructTpl = `
-// table {{ .Name }}
-type {{ .EntitySingular }} struct {
-	{{range $i, $field := .Fields }}{{ $field.Name }} {{ $field.GoType }}
-	{{end}}
-}
-`
+//go:embed templates/*.tpl
+var defaultTemplates embed.FS
 
-/*********************************************************
- * Scan data from the rows object into the entity
- *********************************************************/
-const scanEntityTpl = `
-// Scan {{.EntitySingular}} from rows object
-func (this *{{.EntitySingular}}) scan(rows scannable) error {
-	{{range $type, $vars := .Vars}}var {{$vars}} {{$type}}
-	{{end}}{{if .Vars}}err := rows.Scan({{ .Params }})
-	if err != nil {
-		return err
-	}{{range $i, $code := .Inits}}
-	{{ $code }}{{end}}
-	return nil{{ else }}return rows.Scan({{ .Params }}){{ end }}
+// helper functions available in the templates. See templates/README.md
+// for the templates and the data passed to them
+var templateFuncs = template.FuncMap{
+	"camel":  camel,
+	"snake":  snake,
+	"plural": inflect.Pluralize,
+	"quote":  strconv.Quote,
 }
-`
 
-/*********************************************************
- * Fetch row(s) from the database
- *********************************************************/
-const findEntityTpl = `
-// find {{ .EntitySingular }}
-func Find{{ .EntitySingular }}(query interface{}, params... interface{}) (*{{ .EntitySingular }}, error) {
-	var sql = "SELECT {{ .EscapedName }}.* FROM {{ .EscapedName }}";
-	// decode the query part
-	{{if .IdentityField}}switch val := query.(type) {
-	case int:
-		sql += " WHERE {{ .EscapedName }}.{{.IdentityField.EscapedName}} = " + strconv.Itoa(val)
-	case string:
-		sql += " " + val
-	default:
-		return nil, errors.New("Unsupported type")
-	}{{else}}if val, ok := query.(string); ok {
-		sql += " " + val
-	} else {
-		return nil, errors.New("Unsupported type")
-	}{{end}}
-	// process
-	entity := &{{ .EntitySingular }}{}
-	if err := entity.scan(theDb.QueryRow(sql, params...)); err != nil {
-		return nil, err
+// parse the embedded templates and the overrides from Config.TemplateDir.
+// Templates are parsed only once
+func (this *Generator) loadTemplates() error {
+	if this.templates != nil {
+		return nil
 	}
-	return entity, nil
-}
+	root := template.New("").Funcs(templateFuncs)
 
-// find all {{ .EntityPlural }}
-func Find{{ .EntityPlural }}(params... interface{}) ([]*{{ .EntitySingular }}, error) {
-	sql := "SELECT {{ .EscapedName }}.* FROM {{ .EscapedName }}"
-	// first param might be extra sql. Rest are parameters
-	if len(params) > 0 {
-		value := params[0]
-		params = params[1:]
-		query, ok := value.(string)
-		if !ok {
-			errors.New("Not supported query type")
-		}
-		sql += " " + query
-	}
-	// execute the query
-	rows, err := theDb.Query(sql, params...)
+	// defaults
+	names, err := fs.Glob(defaultTemplates, "templates/*.tpl")
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	// process rows
-	var entities []*{{ .EntitySingular }}
-	for rows.Next() {
-		entity := &{{ .EntitySingular }}{}
-		if err := entity.scan(rows); err != nil {
-			return nil, err
-		}
-		entities = append(entities, entity)
+		return err
 	}
-	return entities, nil
-}
-`
-
-
-/*********************************************************
- * Save or insert entity into the database
- *********************************************************/
-const entitySaveTpl = `
-// Save {{.EntitySingular}}
-func (this *{{.EntitySingular}}) Save() error {
-	// update or insert?
-	if {{ .IdCheck }} {
-		sql := "INSERT INTO {{ .EscapedName }} ({{ .InsertCols }}) VALUES ({{ .InsertVals }})"
-		result, err := theDb.Exec(sql, {{.InsertParams}})
+	for _, name := range names {
+		src, err := defaultTemplates.ReadFile(name)
 		if err != nil {
 			return err
 		}
-		lastId, err := result.LastInsertId()
-		if err != nil {
+		if _, err := root.New(path.Base(name)).Parse(string(src)); err != nil {
 			return err
 		}
-		this.{{ .AutoIncField.Name }} = lastId
-	} else {
-		sql := "UPDATE {{ .EscapedName }} SET {{ .UpdateVals }} WHERE {{ .Where }}"
-		result, err := theDb.Exec(sql, {{.UpdateParams}})
+	}
+
+	// overrides replace the default of the same name
+	if this.Config.TemplateDir != "" {
+		files, err := filepath.Glob(filepath.Join(this.Config.TemplateDir, "*.tpl"))
 		if err != nil {
 			return err
 		}
-		affected, err := result.RowsAffected();
-		if err != nil {
-			return err
-		} else if affected != 1 {
-			return fmt.Errorf("Wrong number of rows affected. Expected 1. Got %d", affected)
+		for _, file := range files {
+			name := filepath.Base(file)
+			if root.Lookup(name) == nil {
+				return fmt.Errorf("%s: there is no default template %s to override", file, name)
+			}
+			src, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if _, err := root.New(name).Parse(string(src)); err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
 		}
 	}
+
+	// user outputs
+	if err := this.loadExtras(root); err != nil {
+		return err
+	}
+
+	this.templates = root
 	return nil
 }
-`
+
+// execute the named template. Errors carry the template name and line
+func (this *Generator) render(w io.Writer, name string, data interface{}) error {
+	return this.templates.ExecuteTemplate(w, name, data)
+}
+
+// convert snake_case to CamelCase
+func camel(s string) string {
+	parts := strings.Split(strings.ToLower(s), "_")
+	for i := 0; i < len(parts); i++ {
+		parts[i] = strings.Title(parts[i])
+	}
+	return strings.Join(parts, "")
+}
+
+// convert CamelCase to snake_case
+func snake(s string) string {
+	var out []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// word starts after a lower case letter or at the last
+			// letter of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
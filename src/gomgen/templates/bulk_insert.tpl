@@ -0,0 +1,55 @@
+
+// Insert {{.EntityPlural}} using multi-row INSERT statements. All the batches
+// run in one transaction, on failure nothing is inserted and the entities
+// are restored as they were.{{if .AutoIncField}}
+// Auto increment ids are set back on the entities assuming the server
+// allocates consecutive ids for a statement (innodb_autoinc_lock_mode 0 or 1){{end}}
+func Insert{{.EntityPlural}}(ctx context.Context, entities []*{{.EntitySingular}}) (err error) {
+	if len(entities) == 0 {
+		return nil
+	}
+	saved := make([]{{.EntitySingular}}, len(entities))
+	for i, entity := range entities {
+		saved[i] = *entity
+	}
+	defer func() {
+		if err != nil {
+			for i, entity := range entities {
+				*entity = saved[i]
+			}
+		}
+	}()
+
+	rows := make([][]interface{}, len(entities))
+	for i, entity := range entities {
+		if hook, ok := interface{}(entity).(BeforeInserter); ok {
+			if err := hook.BeforeInsert(); err != nil {
+				return err
+			}
+		}
+		{{if .Touch}}entity.touch(true)
+		{{end}}if err := entity.Validate(); err != nil {
+			return err
+		}
+		rows[i] = []interface{}{ {{.Params}} }
+	}
+	return transact(ctx, true, func(db executor) error {
+		head := "INSERT INTO {{ .EscapedName }} ({{ .Cols }}) VALUES "
+		return insertBatches(ctx, db, head, "{{ .Row }}", rows, func(offset, count int, firstId int64) error {
+			{{if .AutoIncField}}if firstId != 0 {
+				for i := 0; i < count; i++ {
+					entities[offset+i].{{ .AutoIncField.Name }} = {{ .IdValue }}
+				}
+			}
+			{{end}}for _, entity := range entities[offset : offset+count] {
+				if hook, ok := interface{}(entity).(AfterInserter); ok {
+					if err := hook.AfterInsert(); err != nil {
+						return err
+					}
+				}
+				entity.takeSnapshot()
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,43 @@
+
+// related {{ .Name }}. Loaded from the database on first use
+func (this *{{ .Table.EntitySingular }}) {{ .Name }}() ({{ .CacheType }}, error) {
+	if !this.{{ .CacheName }}Loaded {
+		return this.Reload{{ .Name }}()
+	}
+	return this.{{ .CacheName }}, nil
+}
+
+// load related {{ .Name }} from the database again
+func (this *{{ .Table.EntitySingular }}) Reload{{ .Name }}() ({{ .CacheType }}, error) {
+	related, err := this.Find{{ .Name }}()
+	{{if .Single}}if err == sql.ErrNoRows {
+		related, err = nil, nil
+	}
+	{{end}}if err != nil {
+		return nil, err
+	}
+	this.{{ .CacheName }} = related
+	this.{{ .CacheName }}Loaded = true
+	return related, nil
+}
+{{if .Single}}
+// set related {{ .Name }} and {{ .Column.Name }} pointing to it
+func (this *{{ .Table.EntitySingular }}) Set{{ .Name }}(related {{ .CacheType }}) {
+	this.{{ .CacheName }} = related
+	this.{{ .CacheName }}Loaded = true
+	if related != nil {
+		{{ .SetKey }}
+	}{{if .Nullable}} else {
+		this.{{ .Column.Name }} = {{ .Column.Zero }}
+	}{{end}}
+}
+{{else if not .Through}}
+// set related {{ .Name }} and point their {{ .TargetColumn.Name }} to this {{ .Table.EntitySingular }}
+func (this *{{ .Table.EntitySingular }}) Set{{ .Name }}(related {{ .CacheType }}) {
+	this.{{ .CacheName }} = related
+	this.{{ .CacheName }}Loaded = true
+	for _, item := range related {
+		{{ .SetKey }}
+	}
+}
+{{end}}
\ No newline at end of file
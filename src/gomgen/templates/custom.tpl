@@ -0,0 +1,4 @@
+package model
+
+// hand written code of {{ .EntitySingular }}. This file is created once
+// and never overwritten by gomgen
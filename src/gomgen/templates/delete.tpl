@@ -0,0 +1,38 @@
+{{if .SoftDelete}}
+// Delete {{.EntitySingular}} by setting {{ .SoftDelete.Name }}. The row is kept.
+// Returns sql.ErrNoRows when the row is missing or already deleted
+func (this *{{.EntitySingular}}) Delete() error {
+	{{if .SoftDelete.Pointer}}deleted := ptr(Clock()){{else}}deleted := sql.NullTime{Time: Clock(), Valid: true}{{end}}
+	err := persist(this, deleteOperation, func(db executor) error {
+		sql := "UPDATE {{ .EscapedName }} SET {{ .SoftDelete.EscapedName }} = ? WHERE {{ .Where }} AND {{ .SoftDelete.EscapedName }} IS NULL"
+		result, err := db.Exec(sql, {{if .SoftDelete.Pointer}}formatPtrTime{{else}}formatNullTime{{end}}("{{ .SoftDelete.Format }}", deleted), {{ .WhereParams }})
+		if err != nil {
+			return err
+		}
+		return affectedRow(result)
+	})
+	if err != nil {
+		return err
+	}
+	this.{{ .SoftDelete.Name }} = deleted
+	if this.snapshot != nil {
+		this.snapshot.{{ .SoftDelete.Name }} = {{if .SoftDelete.Pointer}}ptr(*deleted){{else}}deleted{{end}}
+	}
+	return nil
+}
+
+// Remove {{.EntitySingular}} row from the database. Returns sql.ErrNoRows
+// when the row is missing
+func (this *{{.EntitySingular}}) HardDelete() error {{else}}
+// Delete {{.EntitySingular}} row from the database. Returns sql.ErrNoRows
+// when the row is missing
+func (this *{{.EntitySingular}}) Delete() error {{end}}{
+	return persist(this, deleteOperation, func(db executor) error {
+		sql := "DELETE FROM {{ .EscapedName }} WHERE {{ .Where }}"
+		result, err := db.Exec(sql, {{ .WhereParams }})
+		if err != nil {
+			return err
+		}
+		return affectedRow(result)
+	})
+}
@@ -0,0 +1,16 @@
+digraph {{ quote .Schema }} {
+	rankdir=LR;
+	node [shape=plaintext, fontname="Helvetica"];
+	edge [dir=both, fontname="Helvetica"];
+{{ range .Entities }}
+	{{ quote .EntitySingular }} [label=<<table border="0" cellborder="1" cellspacing="0" cellpadding="4">
+		<tr><td colspan="3" bgcolor="lightgrey"><b>{{ .EntitySingular }}</b> ({{ html .Name }})</td></tr>
+		{{- range .Columns }}
+		<tr><td port={{ quote .RealName }} align="left">{{ .Name }}</td><td align="left">{{ html .GoType }}</td><td>{{ .Keys }}</td></tr>
+		{{- end }}
+	</table>>];
+{{- end }}
+{{ range .Edges }}
+	{{ quote .Table.EntitySingular }}:{{ quote .Column.RealName }} -> {{ quote .TargetEntity.EntitySingular }}:{{ quote .TargetColumn.RealName }} [label={{ quote .Name }}, arrowtail={{ if .Single }}teeodot{{ else }}crowodot{{ end }}, arrowhead={{ if .Optional }}teeodot{{ else }}teetee{{ end }}];
+{{- end }}
+}
@@ -0,0 +1,7 @@
+// Autogenerated by gomgen
+package model
+
+import (
+	{{range $k, $v := .Imports}}"{{ $k }}"
+	{{end}}
+)
@@ -0,0 +1,39 @@
+
+// find {{ .EntitySingular }}{{if .SoftDelete}}. Soft deleted rows are skipped unless
+// WithDeleted() or OnlyDeleted() is passed in the params{{end}}
+func Find{{ .EntitySingular }}(query interface{}, params... interface{}) (*{{ .EntitySingular }}, error) {
+	{{if .SoftDelete}}option, params := queryOptions(params)
+	{{end}}var sql = {{ .Select }};
+	// decode the query part
+	{{if .IdentityField}}switch val := query.(type) {
+	case int:
+		sql += " WHERE {{ .EscapedName }}.{{.IdentityField.EscapedName}} = " + strconv.Itoa(val)
+	case string:
+		sql += " " + val
+	default:
+		return nil, errors.New("Unsupported type")
+	}{{else}}if val, ok := query.(string); ok {
+		sql += " " + val
+	} else {
+		return nil, errors.New("Unsupported type")
+	}{{end}}
+	return orm.Find(theDb, {{ .EntitySingular }}Meta, sql, params...)
+}
+
+// find all {{ .EntityPlural }}{{if .SoftDelete}}. Soft deleted rows are skipped unless
+// WithDeleted() or OnlyDeleted() is passed in the params{{end}}
+func Find{{ .EntityPlural }}(params... interface{}) ([]*{{ .EntitySingular }}, error) {
+	{{if .SoftDelete}}option, params := queryOptions(params)
+	{{end}}sql := {{ .Select }}
+	// first param might be extra sql. Rest are parameters
+	if len(params) > 0 {
+		value := params[0]
+		params = params[1:]
+		query, ok := value.(string)
+		if !ok {
+			return nil, errors.New("Not supported query type")
+		}
+		sql += " " + query
+	}
+	return orm.All(theDb, {{ .EntitySingular }}Meta, sql, params...)
+}
@@ -0,0 +1,368 @@
+{{ template "file.tpl" . }}
+
+// object can be scanned. Row, Rows
+type scannable = orm.Scanner
+
+// database connnection
+var theDb *sql.DB
+
+// optimistic locking failure. The row was changed or removed
+// since the entity was loaded
+type ErrStaleEntity struct {
+	Table   string
+	Version int64
+}
+
+// describe the error
+func (this *ErrStaleEntity) Error() string {
+	return fmt.Sprintf("stale %s entity: version %d was changed or removed", this.Table, this.Version)
+}
+
+// option for the finders of soft deletable entities
+type QueryOption int
+
+const (
+	withoutDeleted QueryOption = iota
+	withDeleted
+	onlyDeleted
+)
+
+// include soft deleted rows in the results
+func WithDeleted() QueryOption {
+	return withDeleted
+}
+
+// find only soft deleted rows
+func OnlyDeleted() QueryOption {
+	return onlyDeleted
+}
+
+// separate query options from the query params
+func queryOptions(params []interface{}) (QueryOption, []interface{}) {
+	option := withoutDeleted
+	var rest []interface{}
+	for _, param := range params {
+		if value, ok := param.(QueryOption); ok {
+			option = value
+		} else {
+			rest = append(rest, param)
+		}
+	}
+	return option, rest
+}
+
+// source of the rows filtered on the deletion column. The derived
+// table keeps the table name so query conditions work unchanged
+func (this QueryOption) from(table, column string) string {
+	switch this {
+	case withDeleted:
+		return table
+	case onlyDeleted:
+		return "(SELECT * FROM " + table + " WHERE " + column + " IS NOT NULL) AS " + table
+	default:
+		return "(SELECT * FROM " + table + " WHERE " + column + " IS NULL) AS " + table
+	}
+}
+{{if index .Imports "time"}}
+// clock for the managed timestamps and soft deletes. Replace it
+// for deterministic tests
+var Clock = time.Now
+
+// parse nullable time column
+func parseNullTime(layout string, value sql.NullString) sql.NullTime {
+	if !value.Valid {
+		return sql.NullTime{}
+	}
+	t, err := time.Parse(layout, value.String)
+	return sql.NullTime{Time: t, Valid: err == nil}
+}
+
+// bind nullable time as a query param
+func formatNullTime(layout string, value sql.NullTime) interface{} {
+	if !value.Valid {
+		return nil
+	}
+	return value.Time.Format(layout)
+}
+
+// parse nullable time column into a pointer
+func parsePtrTime(layout string, value sql.NullString) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t, err := time.Parse(layout, value.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// bind time pointer as a query param
+func formatPtrTime(layout string, value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.Format(layout)
+}
+{{end}}
+// constraint violation of a single field
+type FieldError struct {
+	Field   string
+	Column  string
+	Message string
+}
+
+// describe the error
+func (this *FieldError) Error() string {
+	return this.Column + " " + this.Message
+}
+
+// constraint violations of the entity fields
+type ValidationErrors []*FieldError
+
+// describe the errors
+func (this ValidationErrors) Error() string {
+	messages := make([]string, len(this))
+	for i, err := range this {
+		messages[i] = err.Error()
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// list of n query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+{{if index .Imports "reflect"}}// compare values of the custom typed fields
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+{{end}}// pointer to a copy of the value
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// check if value is in the list
+func oneOf(value string, values ...string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// statement executor. The database or a transaction
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// run fn on the database, or in a transaction when useTx is set.
+// The transaction is rolled back when fn fails
+func transact(ctx context.Context, useTx bool, fn func(db executor) error) error {
+	if !useTx {
+		return fn(theDb)
+	}
+	tx, err := theDb.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// optional lifecycle hooks. Implement them on the entities in hand
+// written files of this package. An error from a Before hook aborts
+// the operation. After hooks run in the transaction of the operation
+// and an error rolls it back. Failed inserts and updates restore the
+// entity as it was before the operation
+type BeforeInserter interface {
+	BeforeInsert() error
+}
+
+type AfterInserter interface {
+	AfterInsert() error
+}
+
+type BeforeUpdater interface {
+	BeforeUpdate() error
+}
+
+type AfterUpdater interface {
+	AfterUpdate() error
+}
+
+type BeforeDeleter interface {
+	BeforeDelete() error
+}
+
+type AfterDeleter interface {
+	AfterDelete() error
+}
+
+// called after the entity is loaded from the database
+type AfterLoader interface {
+	AfterLoad() error
+}
+
+// persistence operation for choosing the hooks
+type operation int
+
+const (
+	insertOperation operation = iota
+	updateOperation
+	deleteOperation
+)
+
+// get the before and after hooks the entity implements
+func hooks(entity interface{}, op operation) (before, after func() error) {
+	switch op {
+	case insertOperation:
+		if hook, ok := entity.(BeforeInserter); ok {
+			before = hook.BeforeInsert
+		}
+		if hook, ok := entity.(AfterInserter); ok {
+			after = hook.AfterInsert
+		}
+	case updateOperation:
+		if hook, ok := entity.(BeforeUpdater); ok {
+			before = hook.BeforeUpdate
+		}
+		if hook, ok := entity.(AfterUpdater); ok {
+			after = hook.AfterUpdate
+		}
+	case deleteOperation:
+		if hook, ok := entity.(BeforeDeleter); ok {
+			before = hook.BeforeDelete
+		}
+		if hook, ok := entity.(AfterDeleter); ok {
+			after = hook.AfterDelete
+		}
+	}
+	return before, after
+}
+
+// run the statements in fn surrounded by the entity hooks
+func persist(entity interface{}, op operation, fn func(db executor) error) error {
+	before, after := hooks(entity, op)
+	if before != nil {
+		if err := before(); err != nil {
+			return err
+		}
+	}
+	return transact(context.Background(), after != nil, func(db executor) error {
+		if err := fn(db); err != nil {
+			return err
+		}
+		if after != nil {
+			return after()
+		}
+		return nil
+	})
+}
+
+// check that the statement hit the row, sql.ErrNoRows when it did not
+func affectedRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// layout of the times in the json encoding
+var JsonTimeLayout = {{ printf "%q" .Config.JsonTimeLayout }}
+{{if index .Imports "encoding/json"}}
+// decoded json value. Set tells if the key was present, Value is
+// nil when it was null
+type jsonValue[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// remember the key was present and decode the value
+func (this *jsonValue[T]) UnmarshalJSON(b []byte) error {
+	this.Set = true
+	return json.Unmarshal(b, &this.Value)
+}
+{{end}}
+// register db object for the models. Verify checks that the
+// database matches the schema the models were generated from
+func Register(db *sql.DB) error {
+	theDb = db
+	return nil
+}
+
+// limits for the multi-row inserts. MySQL accepts at most 65535
+// placeholders in a statement, and the statement must fit into
+// max_allowed_packet (4MB by default before MySQL 8.0)
+var (
+	MaxPlaceholders = 65535
+	MaxPacketSize   = 4 << 20
+)
+
+// estimated size of the value in the statement sent to the server
+func paramSize(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return 2*len(v) + 2
+	case []byte:
+		return 2*len(v) + 3
+	default:
+		return 24
+	}
+}
+
+// run multi-row inserts of the rows in batches that fit within
+// MaxPlaceholders and MaxPacketSize. done is called after each batch
+// with the offset of its first row, number of rows inserted and the
+// id generated for the first row
+func insertBatches(ctx context.Context, db executor, head, row string, rows [][]interface{}, done func(offset, count int, firstId int64) error) error {
+	for start := 0; start < len(rows); {
+		params := append([]interface{}{}, rows[start]...)
+		size := len(head) + len(row)
+		for _, value := range rows[start] {
+			size += paramSize(value)
+		}
+
+		// extend the batch while the limits allow
+		end := start + 1
+		for ; end < len(rows); end++ {
+			rowSize := len(row) + 2
+			for _, value := range rows[end] {
+				rowSize += paramSize(value)
+			}
+			if len(params)+len(rows[end]) > MaxPlaceholders || size+rowSize > MaxPacketSize {
+				break
+			}
+			params = append(params, rows[end]...)
+			size += rowSize
+		}
+		query := head + row + strings.Repeat(", "+row, end-start-1)
+
+		// execute
+		result, err := db.ExecContext(ctx, query, params...)
+		if err != nil {
+			return err
+		}
+		firstId, err := result.LastInsertId()
+		if err != nil {
+			firstId = 0
+		}
+		if err := done(start, end-start, firstId); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+
+// json representation of {{ .EntitySingular }}
+type {{ .Name }} struct {
+	{{range $i, $field := .Fields}}{{ $field.Name }} {{ $field.Type }}{{if $field.JsonTag}} `json:"{{ $field.JsonTag }}"`{{end}}
+	{{end}}
+}
+
+// encode {{ .EntitySingular }} as json
+func (this {{ .EntitySingular }}) MarshalJSON() ([]byte, error) {
+	var data {{ .Name }}
+	{{range $i, $field := .Fields}}{{ $field.Marshal }}
+	{{end}}return json.Marshal(data)
+}
+
+// decode {{ .EntitySingular }} from json. Fields missing from the json are
+// left unchanged
+func (this *{{ .EntitySingular }}) UnmarshalJSON(b []byte) error {
+	var data struct {
+		{{range $i, $field := .Fields}}{{ $field.Name }} jsonValue[{{ $field.DecodeType }}]{{if $field.JsonTag}} `json:"{{ $field.JsonTag }}"`{{end}}
+		{{end}}
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	{{range $i, $field := .Fields}}{{ $field.Unmarshal }}
+	{{end}}return nil
+}
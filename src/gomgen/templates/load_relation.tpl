@@ -0,0 +1,66 @@
+
+// preload {{ .Name }} of the {{ .Table.EntityPlural }} with {{if .Through}}two queries{{else}}a single query{{end}}
+func Load{{ .Table.EntityPlural }}{{ .Name }}(entities []*{{ .Table.EntitySingular }}) error {
+	// keys of the entities
+	var keys []interface{}
+	seen := make(map[interface{}]bool)
+	for _, entity := range entities {
+		{{if .KeyValid}}if !{{ .KeyValid }} {
+			continue
+		}
+		{{end}}if !seen[{{ .Key }}] {
+			seen[{{ .Key }}] = true
+			keys = append(keys, {{ .Key }})
+		}
+	}
+	{{if .Single}}related := make(map[interface{}]*{{ .TargetEntity.EntitySingular }}){{else}}related := make(map[interface{}][]*{{ .TargetEntity.EntitySingular }}){{end}}
+	if len(keys) > 0 {
+		{{if .Through}}// connections to the targets
+		sql := "WHERE {{ .MiddleEntity.EscapedName }}.{{ .MiddleSrcColumn.EscapedName }} IN (" + placeholders(len(keys)) + ")"
+		links, err := Find{{ .MiddleEntity.EntityPlural }}(append([]interface{}{sql}, keys...)...)
+		if err != nil {
+			return err
+		}
+		var targetKeys []interface{}
+		for _, link := range links {
+			{{if .LinkDstValid}}if {{ .LinkDstValid }} {
+				targetKeys = append(targetKeys, {{ .LinkDst }})
+			}{{else}}targetKeys = append(targetKeys, {{ .LinkDst }}){{end}}
+		}
+		if len(targetKeys) > 0 {
+			sql := "WHERE {{ .TargetEntity.EscapedName }}.{{ .TargetColumn.EscapedName }} IN (" + placeholders(len(targetKeys)) + ")"
+			found, err := Find{{ .TargetEntity.EntityPlural }}(append([]interface{}{sql}, targetKeys...)...)
+			if err != nil {
+				return err
+			}
+			targets := make(map[interface{}]*{{ .TargetEntity.EntitySingular }})
+			for _, item := range found {
+				targets[{{ .ItemKey }}] = item
+			}
+			for _, link := range links {
+				if item, ok := targets[{{ .LinkDst }}]; ok {
+					related[{{ .LinkSrc }}] = append(related[{{ .LinkSrc }}], item)
+				}
+			}
+		}{{else}}sql := "WHERE {{ .TargetEntity.EscapedName }}.{{ .TargetColumn.EscapedName }} IN (" + placeholders(len(keys)) + ")"
+		found, err := Find{{ .TargetEntity.EntityPlural }}(append([]interface{}{sql}, keys...)...)
+		if err != nil {
+			return err
+		}
+		for _, item := range found {
+			{{if .ItemValid}}if !{{ .ItemValid }} {
+				continue
+			}
+			{{end}}{{if .Single}}related[{{ .ItemKey }}] = item{{else}}related[{{ .ItemKey }}] = append(related[{{ .ItemKey }}], item){{end}}
+		}{{end}}
+	}
+	// attach to the entities
+	for _, entity := range entities {
+		entity.{{ .CacheName }} = {{if .KeyValid}}nil
+		if {{ .KeyValid }} {
+			entity.{{ .CacheName }} = related[{{ .Key }}]
+		}{{else}}related[{{ .Key }}]{{end}}
+		entity.{{ .CacheName }}Loaded = true
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+
+// find {{ .TargetEntity.EntityPlural }} related through {{ .MiddleEntity.Name }}
+func (this *{{ .Table.EntitySingular }}) Find{{ .Name }}() ([]*{{ .TargetEntity.EntitySingular }}, error) {
+	sql := "WHERE {{ .TargetEntity.EscapedName }}.{{ .TargetColumn.EscapedName }} IN (SELECT {{ .MiddleEntity.EscapedName }}.{{ .MiddleDstColumn.EscapedName }} FROM {{ .MiddleEntity.EscapedName }} WHERE {{ .MiddleEntity.EscapedName }}.{{ .MiddleSrcColumn.EscapedName }} = ?)"
+	return Find{{ .TargetEntity.EntityPlural }}(sql, this.{{ .Column.Name }})
+}
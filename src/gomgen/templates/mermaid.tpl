@@ -0,0 +1,11 @@
+erDiagram
+{{- range .Entities }}
+    {{ .EntitySingular }} {
+    {{- range .Columns }}
+        {{ .MermaidType }} {{ .Name }}{{ with .Keys }} {{ . }}{{ end }} "{{ .RealName }}{{ if .Nullable }}, nullable{{ end }}"
+    {{- end }}
+    }
+{{- end }}
+{{- range .Edges }}
+    {{ .TargetEntity.EntitySingular }} {{ if .Optional }}|o{{ else }}||{{ end }}--{{ if .Single }}o|{{ else }}o{{ "{" }}{{ end }} {{ .Table.EntitySingular }} : {{ quote .Name }}
+{{- end }}
@@ -0,0 +1,15 @@
+
+// {{ .Name }} table description for the generic orm functions
+var {{ .EntitySingular }}Meta = &orm.Meta[{{ .EntitySingular }}]{
+	Table:    "{{ .EscapedName }}",
+	Columns:  []string{ {{ .Columns }} },
+	Identity: []string{ {{ .Identity }} },
+	Inserted: []string{ {{ .Inserted }} },
+	Scan:     (*{{ .EntitySingular }}).scan,
+	Values: func(this *{{ .EntitySingular }}) []interface{} {
+		return []interface{}{ {{ .Values }} }
+	},{{if .AutoIncField}}
+	SetId: func(this *{{ .EntitySingular }}, id int64) {
+		this.{{ .AutoIncField.Name }} = {{ .IdValue }}
+	},{{end}}
+}
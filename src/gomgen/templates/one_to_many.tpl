@@ -0,0 +1,6 @@
+
+// find related {{ .TargetEntity.EntityPlural }}
+func (this *{{ .Table.EntitySingular }}) Find{{ .Name }}() ([]*{{ .TargetEntity.EntitySingular }}, error) {
+	sql := "WHERE {{ .TargetEntity.EscapedName }}.{{ .TargetColumn.EscapedName }} = ?"
+	return Find{{ .TargetEntity.EntityPlural }}(sql, this.{{ .Column.Name }})
+}
@@ -0,0 +1,6 @@
+
+// find related {{ .TargetEntity.EntitySingular }}
+func (this *{{ .Table.EntitySingular }}) Find{{ .Name }}() (*{{ .TargetEntity.EntitySingular }}, error) {
+	sql := "WHERE {{ .TargetEntity.EscapedName }}.{{ .TargetColumn.EscapedName }} = ?"
+	return Find{{ .TargetEntity.EntitySingular }}(sql, this.{{ .Column.Name }})
+}
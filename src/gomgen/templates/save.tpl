@@ -0,0 +1,140 @@
+{{if or .SetCreated .SetUpdated}}
+// set the managed timestamps to the current time
+func (this *{{.EntitySingular}}) touch(insert bool) {
+	now := Clock()
+	{{if .SetCreated}}if insert {
+		{{ .SetCreated }}
+	}
+	{{end}}{{ .SetUpdated }}
+}
+{{end}}
+// Insert {{.EntitySingular}} as a new row
+func (this *{{.EntitySingular}}) Insert() error {
+	saved := *this
+	err := persist(this, insertOperation, func(db executor) error {
+		{{if or .SetCreated .SetUpdated}}this.touch(true)
+		{{end}}if err := this.Validate(); err != nil {
+			return err
+		}
+		return orm.Insert(db, {{.EntitySingular}}Meta, this)
+	})
+	if err != nil {
+		// failed operations leave the entity unchanged
+		*this = saved
+		return err
+	}
+	this.takeSnapshot()
+	return nil
+}
+{{if .HasUpdate}}
+// changed columns and their values since {{.EntitySingular}} was loaded or saved.
+// Entities never loaded from the database have all columns changed
+func (this *{{.EntitySingular}}) changes() (cols []string, params []interface{}) {
+	{{range $i, $change := .Changes}}if this.snapshot == nil || {{ $change.Cond }} {
+		cols = append(cols, "{{ $change.Col }}")
+		params = append(params, {{ $change.Param }})
+	}
+	{{end}}return cols, params
+}
+
+// names of the columns changed since {{.EntitySingular}} was loaded or saved
+func (this *{{.EntitySingular}}) Changed() []string {
+	cols, _ := this.changes()
+	for i, col := range cols {
+		cols[i] = strings.Trim(col, "`")
+	}
+	return cols
+}
+
+// check if {{.EntitySingular}} has unsaved changes
+func (this *{{.EntitySingular}}) IsDirty() bool {
+	cols, _ := this.changes()
+	return len(cols) > 0
+}
+
+// Update changed columns of existing {{.EntitySingular}} row{{if .VersionField}}.
+// Returns *ErrStaleEntity when {{ .VersionField.Name }} no longer matches the row{{end}}
+func (this *{{.EntitySingular}}) Update() error {
+	if !this.IsDirty() {
+		return nil
+	}
+	saved := *this
+	err := persist(this, updateOperation, func(db executor) error {
+		{{if .SetUpdated}}this.touch(false)
+		{{end}}if err := this.Validate(); err != nil {
+			return err
+		}
+		cols, params := this.changes()
+		{{if .VersionField}}sql := "UPDATE {{ .EscapedName }} SET " + strings.Join(cols, " = ?, ") + " = ?, {{ .VersionField.EscapedName }} = {{ .VersionField.EscapedName }} + 1 WHERE {{ .Where }} AND {{ .VersionField.EscapedName }} = ?"
+		result, err := db.Exec(sql, append(params, {{.WhereParams}}, this.{{ .VersionField.Name }})...){{else}}sql := "UPDATE {{ .EscapedName }} SET " + strings.Join(cols, " = ?, ") + " = ? WHERE {{ .Where }}"
+		result, err := db.Exec(sql, append(params, {{.WhereParams}})...){{end}}
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected();
+		if err != nil {
+			return err
+		} else if affected != 1 {
+			{{if .VersionField}}if affected == 0 {
+				return &ErrStaleEntity{Table: "{{ .Name }}", Version: this.{{ .VersionField.Name }}}
+			}
+			{{end}}return fmt.Errorf("Wrong number of rows affected. Expected 1. Got %d", affected)
+		}
+		return nil
+	})
+	if err != nil {
+		*this = saved
+		return err
+	}
+	{{if .VersionField}}// the row has the new version only once committed
+	this.{{ .VersionField.Name }}++
+	{{end}}this.takeSnapshot()
+	return nil
+}
+{{end}}{{if .Identity}}
+// Insert {{.EntitySingular}} or update the row with the same key. Runs the insert hooks
+func (this *{{.EntitySingular}}) Upsert() error {
+	saved := *this
+	err := persist(this, insertOperation, func(db executor) error {
+		{{if or .SetCreated .SetUpdated}}this.touch(true)
+		{{end}}if err := this.Validate(); err != nil {
+			return err
+		}
+		sql := "INSERT INTO {{ .EscapedName }} ({{ .UpsertCols }}) VALUES ({{ .UpsertVals }}) ON DUPLICATE KEY UPDATE {{ .UpsertSet }}"
+		{{if .AutoIncField}}result, err := db.Exec(sql, {{.UpsertParams}})
+		if err != nil {
+			return err
+		}
+		lastId, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		this.{{ .AutoIncField.Name }} = {{ .IdValue }}
+		{{else}}if _, err := db.Exec(sql, {{.UpsertParams}}); err != nil {
+			return err
+		}
+		{{end}}{{if or .Created .VersionField}}// an existing row keeps its creation time and increments its version
+		stored, err := orm.Find(db, {{.EntitySingular}}Meta, {{.EntitySingular}}Meta.Select("")+" WHERE {{ .Where }}", {{ .WhereParams }})
+		if err != nil {
+			return err
+		}
+		{{if .Created}}this.{{ .Created.Name }} = stored.{{ .Created.Name }}
+		{{end}}{{if .VersionField}}this.{{ .VersionField.Name }} = stored.{{ .VersionField.Name }}
+		{{end}}{{end}}return nil
+	})
+	if err != nil {
+		*this = saved
+		return err
+	}
+	this.takeSnapshot()
+	return nil
+}
+{{end}}{{if .HasSave}}
+// Save {{.EntitySingular}}. Insert when {{ .AutoIncField.Name }} is not set, update changes otherwise
+func (this *{{.EntitySingular}}) Save() error {
+	if this.{{ .AutoIncField.Name }} == 0 {
+		return this.Insert()
+	}
+	return this.Update()
+}
+{{end}}
\ No newline at end of file
@@ -0,0 +1,15 @@
+
+// Scan {{.EntitySingular}} from rows object
+func (this *{{.EntitySingular}}) scan(rows scannable) error {
+	{{range $type, $vars := .Vars}}var {{$vars}} {{$type}}
+	{{end}}err := rows.Scan({{ .Params }})
+	if err != nil {
+		return err
+	}{{range $i, $code := .Inits}}
+	{{ $code }}{{end}}
+	this.takeSnapshot()
+	if hook, ok := interface{}(this).(AfterLoader); ok {
+		return hook.AfterLoad()
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+
+// sha256 of the tables and columns the models were generated from
+const SchemaFingerprint = {{ quote .Fingerprint }}
+
+// column the models were generated from
+type schemaColumn struct {
+	name     string
+	typ      string
+	nullable bool
+}
+
+// tables and columns the models were generated from
+var schemaTables = []struct {
+	name    string
+	columns []schemaColumn
+}{
+{{- range .Tables }}
+	{ {{ quote .Name }}, []schemaColumn{
+	{{- range .Columns }}
+		{ {{ quote .Name }}, {{ quote .Type }}, {{ .Nullable }} },
+	{{- end }}
+	} },
+{{- end }}
+}
+
+// kind of difference between the database and the models
+type DriftKind int
+
+const (
+	MissingTable DriftKind = iota
+	MissingColumn
+	TypeMismatch
+	NullabilityMismatch
+	ExtraColumn
+)
+
+// describe the kind
+func (this DriftKind) String() string {
+	switch this {
+	case MissingTable:
+		return "missing table"
+	case MissingColumn:
+		return "missing column"
+	case TypeMismatch:
+		return "type mismatch"
+	case NullabilityMismatch:
+		return "nullability mismatch"
+	case ExtraColumn:
+		return "extra column"
+	}
+	return fmt.Sprintf("DriftKind(%d)", int(this))
+}
+
+// difference between the database and the models
+type Drift struct {
+	Kind     DriftKind
+	Table    string
+	Column   string // empty for the missing tables
+	Expected string // type or nullability the models were generated for
+	Actual   string // type or nullability in the database
+}
+
+// describe the difference
+func (this Drift) String() string {
+	name := this.Table
+	if this.Column != "" {
+		name += "." + this.Column
+	}
+	switch this.Kind {
+	case TypeMismatch, NullabilityMismatch:
+		return fmt.Sprintf("%s: %v, expected %s, got %s", name, this.Kind, this.Expected, this.Actual)
+	case ExtraColumn:
+		return fmt.Sprintf("%s: %v %s", name, this.Kind, this.Actual)
+	}
+	return fmt.Sprintf("%s: %v", name, this.Kind)
+}
+
+// database does not match the schema the models were generated from
+type SchemaDriftError struct {
+	Fingerprint string
+	Drifts      []Drift
+}
+
+// describe the error
+func (this *SchemaDriftError) Error() string {
+	messages := make([]string, len(this.Drifts))
+	for i, drift := range this.Drifts {
+		messages[i] = drift.String()
+	}
+	return "schema drift: " + strings.Join(messages, "; ")
+}
+
+// column type without the integer display width, which MySQL 8 does
+// not report
+func columnType(t string) string {
+	t = strings.ToLower(t)
+	for _, name := range []string{"tinyint", "smallint", "mediumint", "int", "bigint"} {
+		if strings.HasPrefix(t, name+"(") {
+			if end := strings.Index(t, ")"); end > 0 {
+				return name + t[end+1:]
+			}
+		}
+	}
+	return t
+}
+
+// nullability of the column in the drift report
+func nullability(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+// compare the tables of the registered database with the schema the
+// models were generated from. Differences are returned as
+// *SchemaDriftError. Tables unknown to the models are ignored
+func Verify(ctx context.Context) error {
+	if theDb == nil {
+		return errors.New("no database registered")
+	}
+
+	// columns of the current database
+	rows, err := theDb.QueryContext(ctx, "SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	actual := map[string][]schemaColumn{}
+	for rows.Next() {
+		var table, nullable string
+		var column schemaColumn
+		if err := rows.Scan(&table, &column.name, &column.typ, &nullable); err != nil {
+			return err
+		}
+		column.typ = columnType(column.typ)
+		column.nullable = nullable == "YES"
+		actual[table] = append(actual[table], column)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// compare
+	var drifts []Drift
+	for _, table := range schemaTables {
+		columns, ok := actual[table.name]
+		if !ok {
+			drifts = append(drifts, Drift{Kind: MissingTable, Table: table.name})
+			continue
+		}
+		found := map[string]schemaColumn{}
+		for _, column := range columns {
+			found[column.name] = column
+		}
+		for _, expected := range table.columns {
+			column, ok := found[expected.name]
+			if !ok {
+				drifts = append(drifts, Drift{Kind: MissingColumn, Table: table.name, Column: expected.name})
+				continue
+			}
+			delete(found, expected.name)
+			if column.typ != expected.typ {
+				drifts = append(drifts, Drift{TypeMismatch, table.name, expected.name, expected.typ, column.typ})
+			}
+			if column.nullable != expected.nullable {
+				drifts = append(drifts, Drift{NullabilityMismatch, table.name, expected.name, nullability(expected.nullable), nullability(column.nullable)})
+			}
+		}
+		for _, column := range columns {
+			if _, ok := found[column.name]; ok {
+				drifts = append(drifts, Drift{Kind: ExtraColumn, Table: table.name, Column: column.name, Actual: column.typ})
+			}
+		}
+	}
+	if len(drifts) > 0 {
+		return &SchemaDriftError{Fingerprint: SchemaFingerprint, Drifts: drifts}
+	}
+	return nil
+}
+
+// statements creating the tables of the models
+var schemaDDL = []string{
+{{- range .DDL }}
+	{{ quote . }},
+{{- end }}
+}
+
+// create the tables of the models in the registered database. Meant
+// for spinning up empty schemas in integration tests
+func CreateSchema(ctx context.Context) error {
+	if theDb == nil {
+		return errors.New("no database registered")
+	}
+	for _, stmt := range schemaDDL {
+		if _, err := theDb.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
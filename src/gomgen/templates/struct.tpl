@@ -0,0 +1,26 @@
+
+// table {{ .Name }}
+type {{ .EntitySingular }} struct {
+	{{range $i, $field := .Fields }}{{ $field.Name }} {{ $field.GoType }}{{if $field.Tag}} `{{ $field.Tag }}`{{end}}
+	{{end}}{{if .Virtual}}
+	// fields not stored in the database
+	{{range $i, $field := .Virtual }}{{ $field.Name }} {{ $field.GoType }}{{if $field.Tag}} `{{ $field.Tag }}`{{end}}
+	{{end}}{{end}}
+	// values as last loaded from or saved to the database
+	snapshot *{{ .EntitySingular }}
+	{{if .Relations}}
+	// related entities cached by the relation getters and Load functions
+	{{range $i, $rel := .Relations}}{{ $rel.CacheName }} {{ $rel.CacheType }}
+	{{ $rel.CacheName }}Loaded bool
+	{{end}}{{end}}
+}
+
+// remember current values to detect changes
+func (this *{{ .EntitySingular }}) takeSnapshot() {
+	snapshot := *this
+	snapshot.snapshot = nil
+	{{range $i, $field := .Fields }}{{if $field.Pointer}}if this.{{ $field.Name }} != nil {
+		snapshot.{{ $field.Name }} = ptr(*this.{{ $field.Name }})
+	}
+	{{end}}{{end}}this.snapshot = &snapshot
+}
@@ -0,0 +1,12 @@
+
+// Validate {{.EntitySingular}} against the column constraints
+func (this *{{.EntitySingular}}) Validate() error {
+	var errs ValidationErrors
+	{{range $i, $check := .Checks}}if {{ $check.Cond }} {
+		errs = append(errs, &FieldError{Field: "{{ $check.Field.Name }}", Column: "{{ $check.Field.RealName }}", Message: {{ $check.Message }}})
+	}
+	{{end}}if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
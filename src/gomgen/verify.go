@@ -0,0 +1,48 @@
+package gomgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// render the schema fingerprint, the Verify function checking the
+// database against the analysed tables and CreateSchema creating them.
+// All three come from the snapshot taken before the hooks, so tables
+// and fields changed by a hook do not make the generated DDL disagree
+// with the schema Verify expects
+func (this *Generator) genSchema(w *bytes.Buffer) error {
+	snapshot := this.Snapshot
+	if snapshot == nil {
+		snapshot = NewSnapshot(this)
+	}
+	data := SchemaData{DDL: DiffSnapshots(&Snapshot{}, snapshot).Up()}
+	hash := sha256.New()
+	for _, t := range snapshot.Tables {
+		table := SchemaTable{Name: t.Name}
+		for _, c := range t.Columns {
+			column := SchemaColumn{Name: c.Name, Type: schemaType(c.Type), Nullable: c.Nullable}
+			fmt.Fprintf(hash, "%s.%s %s %v\n", table.Name, column.Name, column.Type, column.Nullable)
+			table.Columns = append(table.Columns, column)
+		}
+		data.Tables = append(data.Tables, table)
+	}
+	data.Fingerprint = hex.EncodeToString(hash.Sum(nil))
+	return this.render(w, "schema.tpl", data)
+}
+
+// column type without the integer display width, which MySQL 8 does
+// not report. Keep in sync with columnType in schema.tpl
+func schemaType(t string) string {
+	t = strings.ToLower(t)
+	for _, name := range []string{"tinyint", "smallint", "mediumint", "int", "bigint"} {
+		if strings.HasPrefix(t, name+"(") {
+			if end := strings.Index(t, ")"); end > 0 {
+				return name + t[end+1:]
+			}
+		}
+	}
+	return t
+}
@@ -3,13 +3,26 @@ package main
 import (
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
+	"flag"
 	"fmt"
 	"gomgen"
-	"os"
 	"io/ioutil"
+	"os"
+	"strings"
 )
 
 func main() {
+	// options
+	check := flag.Bool("check", false, "compare the generated model with the files on disk and exit with 1 when they differ")
+	snapshot := flag.String("snapshot", "", "read the schema from the snapshot file instead of the database")
+	saveSnapshot := flag.String("save-snapshot", "", "write the analysed schema into the snapshot file")
+	diff := flag.String("diff", "", "print the changes from the snapshot file to the analysed schema instead of generating")
+	migration := flag.String("migration", "", "with -diff write the migration into <name>.up.sql and <name>.down.sql")
+	ddl := flag.String("ddl", "", "write the statements creating the analysed tables into the file")
+	dot := flag.String("dot", "", "write the entity relationship diagram as Graphviz DOT into the file")
+	mermaid := flag.String("mermaid", "", "write the entity relationship diagram as Mermaid erDiagram into the file")
+	flag.Parse()
+
 	// database connection
 	schema := "gomgen"
 	db, err := sql.Open("mysql", "gomgen:dAXthbfKTzNenMRE@tcp(localhost:3306)/gomgen")
@@ -20,11 +33,66 @@ func main() {
 
 	// Gomgen
 	mgen := gomgen.NewGenerator(db, schema)
+	if *snapshot != "" {
+		mgen.Analyzer = &gomgen.SnapshotAnalyzer{Path: *snapshot}
+	}
 
 	// Analyze
 	if err := mgen.Analyse(); err != nil {
 		panic(err)
 	}
+	if *saveSnapshot != "" {
+		if err := mgen.SaveSnapshot(*saveSnapshot); err != nil {
+			panic(err)
+		}
+	}
+
+	if *ddl != "" {
+		if err := ioutil.WriteFile(*ddl, []byte(strings.Join(mgen.DDL(), "\n\n")+"\n"), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	// diagrams
+	if *dot != "" {
+		out, err := mgen.Dot()
+		if err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(*dot, out, 0644); err != nil {
+			panic(err)
+		}
+	}
+	if *mermaid != "" {
+		out, err := mgen.Mermaid()
+		if err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(*mermaid, out, 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	// schema changes since the snapshot
+	if *diff != "" {
+		from, err := gomgen.LoadSnapshot(*diff)
+		if err != nil {
+			panic(err)
+		}
+		changes := gomgen.DiffSnapshots(from, mgen.Snapshot)
+		fmt.Print(changes)
+		if *migration != "" {
+			up := strings.Join(changes.Up(), "\n\n") + "\n"
+			down := strings.Join(changes.Down(), "\n\n") + "\n"
+			if err := ioutil.WriteFile(*migration+".up.sql", []byte(up), 0644); err != nil {
+				panic(err)
+			}
+			if err := ioutil.WriteFile(*migration+".down.sql", []byte(down), 0644); err != nil {
+				panic(err)
+			}
+		}
+		return
+	}
 
 	// generate
 	if err := mgen.Generate(); err != nil {
@@ -34,8 +102,27 @@ func main() {
 	// working path
 	wp, _ := os.Getwd()
 
-	// write the file
-	ioutil.WriteFile(wp + "/model/model.go", mgen.Output.Bytes(), 0644)
+	// report stale files without writing them
+	if *check {
+		diffs, err := mgen.Check(wp + "/model")
+		if err != nil {
+			panic(err)
+		}
+		for _, diff := range diffs {
+			fmt.Print(diff)
+		}
+		if len(diffs) > 0 {
+			fmt.Fprintf(os.Stderr, "%d generated files are out of date\n", len(diffs))
+			os.Exit(1)
+		}
+		fmt.Printf("up to date\n")
+		return
+	}
+
+	// write the files
+	if err := mgen.Write(wp + "/model"); err != nil {
+		panic(err)
+	}
 
 	// done
 	fmt.Printf("done\n")
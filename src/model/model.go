@@ -2,36 +2,625 @@
 package model
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"gomgen/orm"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // object can be scanned. Row, Rows
-type scannable interface {
-	Scan(...interface{}) error
-}
+type scannable = orm.Scanner
 
 // database connnection
 var theDb *sql.DB
 
-// register db object for the models
+// optimistic locking failure. The row was changed or removed
+// since the entity was loaded
+type ErrStaleEntity struct {
+	Table   string
+	Version int64
+}
+
+// describe the error
+func (this *ErrStaleEntity) Error() string {
+	return fmt.Sprintf("stale %s entity: version %d was changed or removed", this.Table, this.Version)
+}
+
+// option for the finders of soft deletable entities
+type QueryOption int
+
+const (
+	withoutDeleted QueryOption = iota
+	withDeleted
+	onlyDeleted
+)
+
+// include soft deleted rows in the results
+func WithDeleted() QueryOption {
+	return withDeleted
+}
+
+// find only soft deleted rows
+func OnlyDeleted() QueryOption {
+	return onlyDeleted
+}
+
+// separate query options from the query params
+func queryOptions(params []interface{}) (QueryOption, []interface{}) {
+	option := withoutDeleted
+	var rest []interface{}
+	for _, param := range params {
+		if value, ok := param.(QueryOption); ok {
+			option = value
+		} else {
+			rest = append(rest, param)
+		}
+	}
+	return option, rest
+}
+
+// source of the rows filtered on the deletion column. The derived
+// table keeps the table name so query conditions work unchanged
+func (this QueryOption) from(table, column string) string {
+	switch this {
+	case withDeleted:
+		return table
+	case onlyDeleted:
+		return "(SELECT * FROM " + table + " WHERE " + column + " IS NOT NULL) AS " + table
+	default:
+		return "(SELECT * FROM " + table + " WHERE " + column + " IS NULL) AS " + table
+	}
+}
+
+// clock for the managed timestamps and soft deletes. Replace it
+// for deterministic tests
+var Clock = time.Now
+
+// parse nullable time column
+func parseNullTime(layout string, value sql.NullString) sql.NullTime {
+	if !value.Valid {
+		return sql.NullTime{}
+	}
+	t, err := time.Parse(layout, value.String)
+	return sql.NullTime{Time: t, Valid: err == nil}
+}
+
+// bind nullable time as a query param
+func formatNullTime(layout string, value sql.NullTime) interface{} {
+	if !value.Valid {
+		return nil
+	}
+	return value.Time.Format(layout)
+}
+
+// parse nullable time column into a pointer
+func parsePtrTime(layout string, value sql.NullString) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t, err := time.Parse(layout, value.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// bind time pointer as a query param
+func formatPtrTime(layout string, value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.Format(layout)
+}
+
+// constraint violation of a single field
+type FieldError struct {
+	Field   string
+	Column  string
+	Message string
+}
+
+// describe the error
+func (this *FieldError) Error() string {
+	return this.Column + " " + this.Message
+}
+
+// constraint violations of the entity fields
+type ValidationErrors []*FieldError
+
+// describe the errors
+func (this ValidationErrors) Error() string {
+	messages := make([]string, len(this))
+	for i, err := range this {
+		messages[i] = err.Error()
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// list of n query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// pointer to a copy of the value
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// check if value is in the list
+func oneOf(value string, values ...string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// statement executor. The database or a transaction
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// run fn on the database, or in a transaction when useTx is set.
+// The transaction is rolled back when fn fails
+func transact(ctx context.Context, useTx bool, fn func(db executor) error) error {
+	if !useTx {
+		return fn(theDb)
+	}
+	tx, err := theDb.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// optional lifecycle hooks. Implement them on the entities in hand
+// written files of this package. An error from a Before hook aborts
+// the operation. After hooks run in the transaction of the operation
+// and an error rolls it back. Failed inserts and updates restore the
+// entity as it was before the operation
+type BeforeInserter interface {
+	BeforeInsert() error
+}
+
+type AfterInserter interface {
+	AfterInsert() error
+}
+
+type BeforeUpdater interface {
+	BeforeUpdate() error
+}
+
+type AfterUpdater interface {
+	AfterUpdate() error
+}
+
+type BeforeDeleter interface {
+	BeforeDelete() error
+}
+
+type AfterDeleter interface {
+	AfterDelete() error
+}
+
+// called after the entity is loaded from the database
+type AfterLoader interface {
+	AfterLoad() error
+}
+
+// persistence operation for choosing the hooks
+type operation int
+
+const (
+	insertOperation operation = iota
+	updateOperation
+	deleteOperation
+)
+
+// get the before and after hooks the entity implements
+func hooks(entity interface{}, op operation) (before, after func() error) {
+	switch op {
+	case insertOperation:
+		if hook, ok := entity.(BeforeInserter); ok {
+			before = hook.BeforeInsert
+		}
+		if hook, ok := entity.(AfterInserter); ok {
+			after = hook.AfterInsert
+		}
+	case updateOperation:
+		if hook, ok := entity.(BeforeUpdater); ok {
+			before = hook.BeforeUpdate
+		}
+		if hook, ok := entity.(AfterUpdater); ok {
+			after = hook.AfterUpdate
+		}
+	case deleteOperation:
+		if hook, ok := entity.(BeforeDeleter); ok {
+			before = hook.BeforeDelete
+		}
+		if hook, ok := entity.(AfterDeleter); ok {
+			after = hook.AfterDelete
+		}
+	}
+	return before, after
+}
+
+// run the statements in fn surrounded by the entity hooks
+func persist(entity interface{}, op operation, fn func(db executor) error) error {
+	before, after := hooks(entity, op)
+	if before != nil {
+		if err := before(); err != nil {
+			return err
+		}
+	}
+	return transact(context.Background(), after != nil, func(db executor) error {
+		if err := fn(db); err != nil {
+			return err
+		}
+		if after != nil {
+			return after()
+		}
+		return nil
+	})
+}
+
+// check that the statement hit the row, sql.ErrNoRows when it did not
+func affectedRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// layout of the times in the json encoding
+var JsonTimeLayout = "2006-01-02T15:04:05Z07:00"
+
+// decoded json value. Set tells if the key was present, Value is
+// nil when it was null
+type jsonValue[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// remember the key was present and decode the value
+func (this *jsonValue[T]) UnmarshalJSON(b []byte) error {
+	this.Set = true
+	return json.Unmarshal(b, &this.Value)
+}
+
+// register db object for the models. Verify checks that the
+// database matches the schema the models were generated from
 func Register(db *sql.DB) error {
 	theDb = db
 	return nil
 }
 
+// limits for the multi-row inserts. MySQL accepts at most 65535
+// placeholders in a statement, and the statement must fit into
+// max_allowed_packet (4MB by default before MySQL 8.0)
+var (
+	MaxPlaceholders = 65535
+	MaxPacketSize   = 4 << 20
+)
+
+// estimated size of the value in the statement sent to the server
+func paramSize(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return 2*len(v) + 2
+	case []byte:
+		return 2*len(v) + 3
+	default:
+		return 24
+	}
+}
+
+// run multi-row inserts of the rows in batches that fit within
+// MaxPlaceholders and MaxPacketSize. done is called after each batch
+// with the offset of its first row, number of rows inserted and the
+// id generated for the first row
+func insertBatches(ctx context.Context, db executor, head, row string, rows [][]interface{}, done func(offset, count int, firstId int64) error) error {
+	for start := 0; start < len(rows); {
+		params := append([]interface{}{}, rows[start]...)
+		size := len(head) + len(row)
+		for _, value := range rows[start] {
+			size += paramSize(value)
+		}
+
+		// extend the batch while the limits allow
+		end := start + 1
+		for ; end < len(rows); end++ {
+			rowSize := len(row) + 2
+			for _, value := range rows[end] {
+				rowSize += paramSize(value)
+			}
+			if len(params)+len(rows[end]) > MaxPlaceholders || size+rowSize > MaxPacketSize {
+				break
+			}
+			params = append(params, rows[end]...)
+			size += rowSize
+		}
+		query := head + row + strings.Repeat(", "+row, end-start-1)
+
+		// execute
+		result, err := db.ExecContext(ctx, query, params...)
+		if err != nil {
+			return err
+		}
+		firstId, err := result.LastInsertId()
+		if err != nil {
+			firstId = 0
+		}
+		if err := done(start, end-start, firstId); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// sha256 of the tables and columns the models were generated from
+const SchemaFingerprint = "d493e8a1969fd5a6275a1f5b184fd11af040fd0930902472f17ed8d5f6ac5e57"
+
+// column the models were generated from
+type schemaColumn struct {
+	name     string
+	typ      string
+	nullable bool
+}
+
+// tables and columns the models were generated from
+var schemaTables = []struct {
+	name    string
+	columns []schemaColumn
+}{
+	{"article", []schemaColumn{
+		{"id", "int", false},
+		{"active", "tinyint", false},
+		{"title", "varchar(45)", false},
+		{"content", "text", false},
+		{"create_date", "datetime", false},
+		{"update_date", "datetime", false},
+		{"category_id", "int", false},
+	}},
+	{"category", []schemaColumn{
+		{"id", "int", false},
+		{"name", "varchar(32)", false},
+	}},
+}
+
+// kind of difference between the database and the models
+type DriftKind int
+
+const (
+	MissingTable DriftKind = iota
+	MissingColumn
+	TypeMismatch
+	NullabilityMismatch
+	ExtraColumn
+)
+
+// describe the kind
+func (this DriftKind) String() string {
+	switch this {
+	case MissingTable:
+		return "missing table"
+	case MissingColumn:
+		return "missing column"
+	case TypeMismatch:
+		return "type mismatch"
+	case NullabilityMismatch:
+		return "nullability mismatch"
+	case ExtraColumn:
+		return "extra column"
+	}
+	return fmt.Sprintf("DriftKind(%d)", int(this))
+}
+
+// difference between the database and the models
+type Drift struct {
+	Kind     DriftKind
+	Table    string
+	Column   string // empty for the missing tables
+	Expected string // type or nullability the models were generated for
+	Actual   string // type or nullability in the database
+}
+
+// describe the difference
+func (this Drift) String() string {
+	name := this.Table
+	if this.Column != "" {
+		name += "." + this.Column
+	}
+	switch this.Kind {
+	case TypeMismatch, NullabilityMismatch:
+		return fmt.Sprintf("%s: %v, expected %s, got %s", name, this.Kind, this.Expected, this.Actual)
+	case ExtraColumn:
+		return fmt.Sprintf("%s: %v %s", name, this.Kind, this.Actual)
+	}
+	return fmt.Sprintf("%s: %v", name, this.Kind)
+}
+
+// database does not match the schema the models were generated from
+type SchemaDriftError struct {
+	Fingerprint string
+	Drifts      []Drift
+}
+
+// describe the error
+func (this *SchemaDriftError) Error() string {
+	messages := make([]string, len(this.Drifts))
+	for i, drift := range this.Drifts {
+		messages[i] = drift.String()
+	}
+	return "schema drift: " + strings.Join(messages, "; ")
+}
+
+// column type without the integer display width, which MySQL 8 does
+// not report
+func columnType(t string) string {
+	t = strings.ToLower(t)
+	for _, name := range []string{"tinyint", "smallint", "mediumint", "int", "bigint"} {
+		if strings.HasPrefix(t, name+"(") {
+			if end := strings.Index(t, ")"); end > 0 {
+				return name + t[end+1:]
+			}
+		}
+	}
+	return t
+}
+
+// nullability of the column in the drift report
+func nullability(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+// compare the tables of the registered database with the schema the
+// models were generated from. Differences are returned as
+// *SchemaDriftError. Tables unknown to the models are ignored
+func Verify(ctx context.Context) error {
+	if theDb == nil {
+		return errors.New("no database registered")
+	}
+
+	// columns of the current database
+	rows, err := theDb.QueryContext(ctx, "SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	actual := map[string][]schemaColumn{}
+	for rows.Next() {
+		var table, nullable string
+		var column schemaColumn
+		if err := rows.Scan(&table, &column.name, &column.typ, &nullable); err != nil {
+			return err
+		}
+		column.typ = columnType(column.typ)
+		column.nullable = nullable == "YES"
+		actual[table] = append(actual[table], column)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// compare
+	var drifts []Drift
+	for _, table := range schemaTables {
+		columns, ok := actual[table.name]
+		if !ok {
+			drifts = append(drifts, Drift{Kind: MissingTable, Table: table.name})
+			continue
+		}
+		found := map[string]schemaColumn{}
+		for _, column := range columns {
+			found[column.name] = column
+		}
+		for _, expected := range table.columns {
+			column, ok := found[expected.name]
+			if !ok {
+				drifts = append(drifts, Drift{Kind: MissingColumn, Table: table.name, Column: expected.name})
+				continue
+			}
+			delete(found, expected.name)
+			if column.typ != expected.typ {
+				drifts = append(drifts, Drift{TypeMismatch, table.name, expected.name, expected.typ, column.typ})
+			}
+			if column.nullable != expected.nullable {
+				drifts = append(drifts, Drift{NullabilityMismatch, table.name, expected.name, nullability(expected.nullable), nullability(column.nullable)})
+			}
+		}
+		for _, column := range columns {
+			if _, ok := found[column.name]; ok {
+				drifts = append(drifts, Drift{Kind: ExtraColumn, Table: table.name, Column: column.name, Actual: column.typ})
+			}
+		}
+	}
+	if len(drifts) > 0 {
+		return &SchemaDriftError{Fingerprint: SchemaFingerprint, Drifts: drifts}
+	}
+	return nil
+}
+
+// statements creating the tables of the models
+var schemaDDL = []string{
+	"CREATE TABLE `article` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n  `active` tinyint(1) NOT NULL,\n  `title` varchar(45) NOT NULL,\n  `content` text NOT NULL,\n  `create_date` datetime NOT NULL,\n  `update_date` datetime NOT NULL,\n  `category_id` int(11) NOT NULL,\n  PRIMARY KEY (`id`),\n  KEY `fk_article_category_idx` (`category_id`)\n);",
+	"CREATE TABLE `category` (\n  `id` int(11) NOT NULL AUTO_INCREMENT,\n  `name` varchar(32) NOT NULL,\n  PRIMARY KEY (`id`)\n);",
+	"ALTER TABLE `article` ADD CONSTRAINT `fk_article_category` FOREIGN KEY (`category_id`) REFERENCES `category` (`id`);",
+}
+
+// create the tables of the models in the registered database. Meant
+// for spinning up empty schemas in integration tests
+func CreateSchema(ctx context.Context) error {
+	if theDb == nil {
+		return errors.New("no database registered")
+	}
+	for _, stmt := range schemaDDL {
+		if _, err := theDb.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // table article
 type Article struct {
-	Id         int64
-	Active     bool
-	Title      string
-	Content    string
-	CreateDate time.Time
-	UpdateDate time.Time
-	CategoryId int64
+	Id         int64     `db:"id" json:"id"`
+	Active     bool      `db:"active" json:"active"`
+	Title      string    `db:"title" json:"title"`
+	Content    string    `db:"content" json:"content"`
+	CreateDate time.Time `db:"create_date" json:"create_date"`
+	UpdateDate time.Time `db:"update_date" json:"update_date"`
+	CategoryId int64     `db:"category_id" json:"category_id"`
+
+	// values as last loaded from or saved to the database
+	snapshot *Article
+
+	// related entities cached by the relation getters and Load functions
+	category       *Category
+	categoryLoaded bool
+}
+
+// remember current values to detect changes
+func (this *Article) takeSnapshot() {
+	snapshot := *this
+	snapshot.snapshot = nil
+	this.snapshot = &snapshot
+}
+
+// article table description for the generic orm functions
+var ArticleMeta = &orm.Meta[Article]{
+	Table:    "`article`",
+	Columns:  []string{"`id`", "`active`", "`title`", "`content`", "`create_date`", "`update_date`", "`category_id`"},
+	Identity: []string{"`id`"},
+	Inserted: []string{"`active`", "`title`", "`content`", "`create_date`", "`update_date`", "`category_id`"},
+	Scan:     (*Article).scan,
+	Values: func(this *Article) []interface{} {
+		return []interface{}{this.Active, this.Title, this.Content, this.CreateDate.Format("2006-01-02 15:04:05"), this.UpdateDate.Format("2006-01-02 15:04:05"), this.CategoryId}
+	},
+	SetId: func(this *Article, id int64) {
+		this.Id = id
+	},
 }
 
 // Scan Article from rows object
@@ -43,12 +632,40 @@ func (this *Article) scan(rows scannable) error {
 	}
 	this.CreateDate, _ = time.Parse("2006-01-02 15:04:05", CreateDate)
 	this.UpdateDate, _ = time.Parse("2006-01-02 15:04:05", UpdateDate)
+	this.takeSnapshot()
+	if hook, ok := interface{}(this).(AfterLoader); ok {
+		return hook.AfterLoad()
+	}
+	return nil
+}
+
+// Validate Article against the column constraints
+func (this *Article) Validate() error {
+	var errs ValidationErrors
+	if this.Id < -2147483648 || this.Id > 2147483647 {
+		errs = append(errs, &FieldError{Field: "Id", Column: "id", Message: "must be between -2147483648 and 2147483647"})
+	}
+	if this.Title == "" {
+		errs = append(errs, &FieldError{Field: "Title", Column: "title", Message: "is required"})
+	}
+	if utf8.RuneCountInString(this.Title) > 45 {
+		errs = append(errs, &FieldError{Field: "Title", Column: "title", Message: "is longer than 45 characters"})
+	}
+	if this.Content == "" {
+		errs = append(errs, &FieldError{Field: "Content", Column: "content", Message: "is required"})
+	}
+	if this.CategoryId < -2147483648 || this.CategoryId > 2147483647 {
+		errs = append(errs, &FieldError{Field: "CategoryId", Column: "category_id", Message: "must be between -2147483648 and 2147483647"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
 // find Article
 func FindArticle(query interface{}, params ...interface{}) (*Article, error) {
-	var sql = "SELECT `article`.* FROM `article`"
+	var sql = ArticleMeta.Select("")
 	// decode the query part
 	switch val := query.(type) {
 	case int:
@@ -58,51 +675,141 @@ func FindArticle(query interface{}, params ...interface{}) (*Article, error) {
 	default:
 		return nil, errors.New("Unsupported type")
 	}
-	// process
-	entity := &Article{}
-	if err := entity.scan(theDb.QueryRow(sql, params...)); err != nil {
-		return nil, err
-	}
-	return entity, nil
+	return orm.Find(theDb, ArticleMeta, sql, params...)
 }
 
 // find all Articles
 func FindArticles(params ...interface{}) ([]*Article, error) {
-	sql := "SELECT `article`.* FROM `article`"
+	sql := ArticleMeta.Select("")
 	// first param might be extra sql. Rest are parameters
 	if len(params) > 0 {
 		value := params[0]
 		params = params[1:]
 		query, ok := value.(string)
 		if !ok {
-			errors.New("Not supported query type")
+			return nil, errors.New("Not supported query type")
 		}
 		sql += " " + query
 	}
-	// execute the query
-	rows, err := theDb.Query(sql, params...)
+	return orm.All(theDb, ArticleMeta, sql, params...)
+}
+
+// set the managed timestamps to the current time
+func (this *Article) touch(insert bool) {
+	now := Clock()
+	if insert {
+		this.CreateDate = now
+	}
+	this.UpdateDate = now
+}
+
+// Insert Article as a new row
+func (this *Article) Insert() error {
+	saved := *this
+	err := persist(this, insertOperation, func(db executor) error {
+		this.touch(true)
+		if err := this.Validate(); err != nil {
+			return err
+		}
+		return orm.Insert(db, ArticleMeta, this)
+	})
 	if err != nil {
-		return nil, err
+		// failed operations leave the entity unchanged
+		*this = saved
+		return err
 	}
-	defer rows.Close()
-	// process rows
-	var entities []*Article
-	for rows.Next() {
-		entity := &Article{}
-		if err := entity.scan(rows); err != nil {
-			return nil, err
+	this.takeSnapshot()
+	return nil
+}
+
+// changed columns and their values since Article was loaded or saved.
+// Entities never loaded from the database have all columns changed
+func (this *Article) changes() (cols []string, params []interface{}) {
+	if this.snapshot == nil || this.Active != this.snapshot.Active {
+		cols = append(cols, "`active`")
+		params = append(params, this.Active)
+	}
+	if this.snapshot == nil || this.Title != this.snapshot.Title {
+		cols = append(cols, "`title`")
+		params = append(params, this.Title)
+	}
+	if this.snapshot == nil || this.Content != this.snapshot.Content {
+		cols = append(cols, "`content`")
+		params = append(params, this.Content)
+	}
+	if this.snapshot == nil || !this.CreateDate.Equal(this.snapshot.CreateDate) {
+		cols = append(cols, "`create_date`")
+		params = append(params, this.CreateDate.Format("2006-01-02 15:04:05"))
+	}
+	if this.snapshot == nil || !this.UpdateDate.Equal(this.snapshot.UpdateDate) {
+		cols = append(cols, "`update_date`")
+		params = append(params, this.UpdateDate.Format("2006-01-02 15:04:05"))
+	}
+	if this.snapshot == nil || this.CategoryId != this.snapshot.CategoryId {
+		cols = append(cols, "`category_id`")
+		params = append(params, this.CategoryId)
+	}
+	return cols, params
+}
+
+// names of the columns changed since Article was loaded or saved
+func (this *Article) Changed() []string {
+	cols, _ := this.changes()
+	for i, col := range cols {
+		cols[i] = strings.Trim(col, "`")
+	}
+	return cols
+}
+
+// check if Article has unsaved changes
+func (this *Article) IsDirty() bool {
+	cols, _ := this.changes()
+	return len(cols) > 0
+}
+
+// Update changed columns of existing Article row
+func (this *Article) Update() error {
+	if !this.IsDirty() {
+		return nil
+	}
+	saved := *this
+	err := persist(this, updateOperation, func(db executor) error {
+		this.touch(false)
+		if err := this.Validate(); err != nil {
+			return err
+		}
+		cols, params := this.changes()
+		sql := "UPDATE `article` SET " + strings.Join(cols, " = ?, ") + " = ? WHERE `id` = ?"
+		result, err := db.Exec(sql, append(params, this.Id)...)
+		if err != nil {
+			return err
 		}
-		entities = append(entities, entity)
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		} else if affected != 1 {
+			return fmt.Errorf("Wrong number of rows affected. Expected 1. Got %d", affected)
+		}
+		return nil
+	})
+	if err != nil {
+		*this = saved
+		return err
 	}
-	return entities, nil
+	this.takeSnapshot()
+	return nil
 }
 
-// Save Article
-func (this *Article) Save() error {
-	// update or insert?
-	if this.Id == 0 {
-		sql := "INSERT INTO `article` (`active`, `title`, `content`, `create_date`, `update_date`, `category_id`) VALUES (?, ?, ?, ?, ?, ?)"
-		result, err := theDb.Exec(sql, this.Active, this.Title, this.Content, this.CreateDate.Format("2006-01-02 15:04:05"), this.UpdateDate.Format("2006-01-02 15:04:05"), this.CategoryId)
+// Insert Article or update the row with the same key. Runs the insert hooks
+func (this *Article) Upsert() error {
+	saved := *this
+	err := persist(this, insertOperation, func(db executor) error {
+		this.touch(true)
+		if err := this.Validate(); err != nil {
+			return err
+		}
+		sql := "INSERT INTO `article` (`id`, `active`, `title`, `content`, `create_date`, `update_date`, `category_id`) VALUES (?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE `id` = LAST_INSERT_ID(`id`), `active` = VALUES(`active`), `title` = VALUES(`title`), `content` = VALUES(`content`), `update_date` = VALUES(`update_date`), `category_id` = VALUES(`category_id`)"
+		result, err := db.Exec(sql, this.Id, this.Active, this.Title, this.Content, this.CreateDate.Format("2006-01-02 15:04:05"), this.UpdateDate.Format("2006-01-02 15:04:05"), this.CategoryId)
 		if err != nil {
 			return err
 		}
@@ -111,20 +818,96 @@ func (this *Article) Save() error {
 			return err
 		}
 		this.Id = lastId
-	} else {
-		sql := "UPDATE `article` SET `active` = ?, `title` = ?, `content` = ?, `create_date` = ?, `update_date` = ?, `category_id` = ? WHERE `id` = ?"
-		result, err := theDb.Exec(sql, this.Active, this.Title, this.Content, this.CreateDate.Format("2006-01-02 15:04:05"), this.UpdateDate.Format("2006-01-02 15:04:05"), this.CategoryId, this.Id)
+		// an existing row keeps its creation time and increments its version
+		stored, err := orm.Find(db, ArticleMeta, ArticleMeta.Select("")+" WHERE `id` = ?", this.Id)
 		if err != nil {
 			return err
 		}
-		affected, err := result.RowsAffected()
+		this.CreateDate = stored.CreateDate
+		return nil
+	})
+	if err != nil {
+		*this = saved
+		return err
+	}
+	this.takeSnapshot()
+	return nil
+}
+
+// Save Article. Insert when Id is not set, update changes otherwise
+func (this *Article) Save() error {
+	if this.Id == 0 {
+		return this.Insert()
+	}
+	return this.Update()
+}
+
+// Insert Articles using multi-row INSERT statements. All the batches
+// run in one transaction, on failure nothing is inserted and the entities
+// are restored as they were.
+// Auto increment ids are set back on the entities assuming the server
+// allocates consecutive ids for a statement (innodb_autoinc_lock_mode 0 or 1)
+func InsertArticles(ctx context.Context, entities []*Article) (err error) {
+	if len(entities) == 0 {
+		return nil
+	}
+	saved := make([]Article, len(entities))
+	for i, entity := range entities {
+		saved[i] = *entity
+	}
+	defer func() {
 		if err != nil {
+			for i, entity := range entities {
+				*entity = saved[i]
+			}
+		}
+	}()
+
+	rows := make([][]interface{}, len(entities))
+	for i, entity := range entities {
+		if hook, ok := interface{}(entity).(BeforeInserter); ok {
+			if err := hook.BeforeInsert(); err != nil {
+				return err
+			}
+		}
+		entity.touch(true)
+		if err := entity.Validate(); err != nil {
 			return err
-		} else if affected != 1 {
-			return fmt.Errorf("Wrong number of rows affected. Expected 1. Got %d", affected)
 		}
+		rows[i] = []interface{}{entity.Active, entity.Title, entity.Content, entity.CreateDate.Format("2006-01-02 15:04:05"), entity.UpdateDate.Format("2006-01-02 15:04:05"), entity.CategoryId}
 	}
-	return nil
+	return transact(ctx, true, func(db executor) error {
+		head := "INSERT INTO `article` (`active`, `title`, `content`, `create_date`, `update_date`, `category_id`) VALUES "
+		return insertBatches(ctx, db, head, "(?, ?, ?, ?, ?, ?)", rows, func(offset, count int, firstId int64) error {
+			if firstId != 0 {
+				for i := 0; i < count; i++ {
+					entities[offset+i].Id = firstId + int64(i)
+				}
+			}
+			for _, entity := range entities[offset : offset+count] {
+				if hook, ok := interface{}(entity).(AfterInserter); ok {
+					if err := hook.AfterInsert(); err != nil {
+						return err
+					}
+				}
+				entity.takeSnapshot()
+			}
+			return nil
+		})
+	})
+}
+
+// Delete Article row from the database. Returns sql.ErrNoRows
+// when the row is missing
+func (this *Article) Delete() error {
+	return persist(this, deleteOperation, func(db executor) error {
+		sql := "DELETE FROM `article` WHERE `id` = ?"
+		result, err := db.Exec(sql, this.Id)
+		if err != nil {
+			return err
+		}
+		return affectedRow(result)
+	})
 }
 
 // find related Category
@@ -133,20 +916,207 @@ func (this *Article) FindCategory() (*Category, error) {
 	return FindCategory(sql, this.CategoryId)
 }
 
+// related Category. Loaded from the database on first use
+func (this *Article) Category() (*Category, error) {
+	if !this.categoryLoaded {
+		return this.ReloadCategory()
+	}
+	return this.category, nil
+}
+
+// load related Category from the database again
+func (this *Article) ReloadCategory() (*Category, error) {
+	related, err := this.FindCategory()
+	if err == sql.ErrNoRows {
+		related, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	this.category = related
+	this.categoryLoaded = true
+	return related, nil
+}
+
+// set related Category and CategoryId pointing to it
+func (this *Article) SetCategory(related *Category) {
+	this.category = related
+	this.categoryLoaded = true
+	if related != nil {
+		this.CategoryId = related.Id
+	}
+}
+
+// preload Category of the Articles with a single query
+func LoadArticlesCategory(entities []*Article) error {
+	// keys of the entities
+	var keys []interface{}
+	seen := make(map[interface{}]bool)
+	for _, entity := range entities {
+		if !seen[entity.CategoryId] {
+			seen[entity.CategoryId] = true
+			keys = append(keys, entity.CategoryId)
+		}
+	}
+	related := make(map[interface{}]*Category)
+	if len(keys) > 0 {
+		sql := "WHERE `category`.`id` IN (" + placeholders(len(keys)) + ")"
+		found, err := FindCategories(append([]interface{}{sql}, keys...)...)
+		if err != nil {
+			return err
+		}
+		for _, item := range found {
+			related[item.Id] = item
+		}
+	}
+	// attach to the entities
+	for _, entity := range entities {
+		entity.category = related[entity.CategoryId]
+		entity.categoryLoaded = true
+	}
+	return nil
+}
+
+// json representation of Article
+type articleJSON struct {
+	Id         int64  `json:"id"`
+	Active     bool   `json:"active"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	CreateDate string `json:"create_date"`
+	UpdateDate string `json:"update_date"`
+	CategoryId int64  `json:"category_id"`
+}
+
+// encode Article as json
+func (this Article) MarshalJSON() ([]byte, error) {
+	var data articleJSON
+	data.Id = this.Id
+	data.Active = this.Active
+	data.Title = this.Title
+	data.Content = this.Content
+	data.CreateDate = this.CreateDate.Format(JsonTimeLayout)
+	data.UpdateDate = this.UpdateDate.Format(JsonTimeLayout)
+	data.CategoryId = this.CategoryId
+	return json.Marshal(data)
+}
+
+// decode Article from json. Fields missing from the json are
+// left unchanged
+func (this *Article) UnmarshalJSON(b []byte) error {
+	var data struct {
+		Id         jsonValue[int64]  `json:"id"`
+		Active     jsonValue[bool]   `json:"active"`
+		Title      jsonValue[string] `json:"title"`
+		Content    jsonValue[string] `json:"content"`
+		CreateDate jsonValue[string] `json:"create_date"`
+		UpdateDate jsonValue[string] `json:"update_date"`
+		CategoryId jsonValue[int64]  `json:"category_id"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if data.Id.Value != nil {
+		this.Id = *data.Id.Value
+	}
+	if data.Active.Value != nil {
+		this.Active = *data.Active.Value
+	}
+	if data.Title.Value != nil {
+		this.Title = *data.Title.Value
+	}
+	if data.Content.Value != nil {
+		this.Content = *data.Content.Value
+	}
+	if data.CreateDate.Value != nil && *data.CreateDate.Value != "" {
+		t, err := time.Parse(JsonTimeLayout, *data.CreateDate.Value)
+		if err != nil {
+			return err
+		}
+		this.CreateDate = t
+	}
+	if data.UpdateDate.Value != nil && *data.UpdateDate.Value != "" {
+		t, err := time.Parse(JsonTimeLayout, *data.UpdateDate.Value)
+		if err != nil {
+			return err
+		}
+		this.UpdateDate = t
+	}
+	if data.CategoryId.Value != nil {
+		this.CategoryId = *data.CategoryId.Value
+	}
+	return nil
+}
+
 // table category
 type Category struct {
-	Id   int64
-	Name string
+	Id   int64  `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+
+	// values as last loaded from or saved to the database
+	snapshot *Category
+
+	// related entities cached by the relation getters and Load functions
+	articles       []*Article
+	articlesLoaded bool
+}
+
+// remember current values to detect changes
+func (this *Category) takeSnapshot() {
+	snapshot := *this
+	snapshot.snapshot = nil
+	this.snapshot = &snapshot
+}
+
+// category table description for the generic orm functions
+var CategoryMeta = &orm.Meta[Category]{
+	Table:    "`category`",
+	Columns:  []string{"`id`", "`name`"},
+	Identity: []string{"`id`"},
+	Inserted: []string{"`name`"},
+	Scan:     (*Category).scan,
+	Values: func(this *Category) []interface{} {
+		return []interface{}{this.Name}
+	},
+	SetId: func(this *Category, id int64) {
+		this.Id = id
+	},
 }
 
 // Scan Category from rows object
 func (this *Category) scan(rows scannable) error {
-	return rows.Scan(&this.Id, &this.Name)
+	err := rows.Scan(&this.Id, &this.Name)
+	if err != nil {
+		return err
+	}
+	this.takeSnapshot()
+	if hook, ok := interface{}(this).(AfterLoader); ok {
+		return hook.AfterLoad()
+	}
+	return nil
+}
+
+// Validate Category against the column constraints
+func (this *Category) Validate() error {
+	var errs ValidationErrors
+	if this.Id < -2147483648 || this.Id > 2147483647 {
+		errs = append(errs, &FieldError{Field: "Id", Column: "id", Message: "must be between -2147483648 and 2147483647"})
+	}
+	if this.Name == "" {
+		errs = append(errs, &FieldError{Field: "Name", Column: "name", Message: "is required"})
+	}
+	if utf8.RuneCountInString(this.Name) > 32 {
+		errs = append(errs, &FieldError{Field: "Name", Column: "name", Message: "is longer than 32 characters"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
 // find Category
 func FindCategory(query interface{}, params ...interface{}) (*Category, error) {
-	var sql = "SELECT `category`.* FROM `category`"
+	var sql = CategoryMeta.Select("")
 	// decode the query part
 	switch val := query.(type) {
 	case int:
@@ -156,51 +1126,109 @@ func FindCategory(query interface{}, params ...interface{}) (*Category, error) {
 	default:
 		return nil, errors.New("Unsupported type")
 	}
-	// process
-	entity := &Category{}
-	if err := entity.scan(theDb.QueryRow(sql, params...)); err != nil {
-		return nil, err
-	}
-	return entity, nil
+	return orm.Find(theDb, CategoryMeta, sql, params...)
 }
 
 // find all Categories
 func FindCategories(params ...interface{}) ([]*Category, error) {
-	sql := "SELECT `category`.* FROM `category`"
+	sql := CategoryMeta.Select("")
 	// first param might be extra sql. Rest are parameters
 	if len(params) > 0 {
 		value := params[0]
 		params = params[1:]
 		query, ok := value.(string)
 		if !ok {
-			errors.New("Not supported query type")
+			return nil, errors.New("Not supported query type")
 		}
 		sql += " " + query
 	}
-	// execute the query
-	rows, err := theDb.Query(sql, params...)
+	return orm.All(theDb, CategoryMeta, sql, params...)
+}
+
+// Insert Category as a new row
+func (this *Category) Insert() error {
+	saved := *this
+	err := persist(this, insertOperation, func(db executor) error {
+		if err := this.Validate(); err != nil {
+			return err
+		}
+		return orm.Insert(db, CategoryMeta, this)
+	})
 	if err != nil {
-		return nil, err
+		// failed operations leave the entity unchanged
+		*this = saved
+		return err
 	}
-	defer rows.Close()
-	// process rows
-	var entities []*Category
-	for rows.Next() {
-		entity := &Category{}
-		if err := entity.scan(rows); err != nil {
-			return nil, err
+	this.takeSnapshot()
+	return nil
+}
+
+// changed columns and their values since Category was loaded or saved.
+// Entities never loaded from the database have all columns changed
+func (this *Category) changes() (cols []string, params []interface{}) {
+	if this.snapshot == nil || this.Name != this.snapshot.Name {
+		cols = append(cols, "`name`")
+		params = append(params, this.Name)
+	}
+	return cols, params
+}
+
+// names of the columns changed since Category was loaded or saved
+func (this *Category) Changed() []string {
+	cols, _ := this.changes()
+	for i, col := range cols {
+		cols[i] = strings.Trim(col, "`")
+	}
+	return cols
+}
+
+// check if Category has unsaved changes
+func (this *Category) IsDirty() bool {
+	cols, _ := this.changes()
+	return len(cols) > 0
+}
+
+// Update changed columns of existing Category row
+func (this *Category) Update() error {
+	if !this.IsDirty() {
+		return nil
+	}
+	saved := *this
+	err := persist(this, updateOperation, func(db executor) error {
+		if err := this.Validate(); err != nil {
+			return err
 		}
-		entities = append(entities, entity)
+		cols, params := this.changes()
+		sql := "UPDATE `category` SET " + strings.Join(cols, " = ?, ") + " = ? WHERE `id` = ?"
+		result, err := db.Exec(sql, append(params, this.Id)...)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		} else if affected != 1 {
+			return fmt.Errorf("Wrong number of rows affected. Expected 1. Got %d", affected)
+		}
+		return nil
+	})
+	if err != nil {
+		*this = saved
+		return err
 	}
-	return entities, nil
+	this.takeSnapshot()
+	return nil
 }
 
-// Save Category
-func (this *Category) Save() error {
-	// update or insert?
-	if this.Id == 0 {
-		sql := "INSERT INTO `category` (`name`) VALUES (?)"
-		result, err := theDb.Exec(sql, this.Name)
+// Insert Category or update the row with the same key. Runs the insert hooks
+func (this *Category) Upsert() error {
+	saved := *this
+	err := persist(this, insertOperation, func(db executor) error {
+		if err := this.Validate(); err != nil {
+			return err
+		}
+		sql := "INSERT INTO `category` (`id`, `name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `id` = LAST_INSERT_ID(`id`), `name` = VALUES(`name`)"
+		result, err := db.Exec(sql, this.Id, this.Name)
 		if err != nil {
 			return err
 		}
@@ -209,18 +1237,184 @@ func (this *Category) Save() error {
 			return err
 		}
 		this.Id = lastId
-	} else {
-		sql := "UPDATE `category` SET `name` = ? WHERE `id` = ?"
-		result, err := theDb.Exec(sql, this.Name, this.Id)
+		return nil
+	})
+	if err != nil {
+		*this = saved
+		return err
+	}
+	this.takeSnapshot()
+	return nil
+}
+
+// Save Category. Insert when Id is not set, update changes otherwise
+func (this *Category) Save() error {
+	if this.Id == 0 {
+		return this.Insert()
+	}
+	return this.Update()
+}
+
+// Insert Categories using multi-row INSERT statements. All the batches
+// run in one transaction, on failure nothing is inserted and the entities
+// are restored as they were.
+// Auto increment ids are set back on the entities assuming the server
+// allocates consecutive ids for a statement (innodb_autoinc_lock_mode 0 or 1)
+func InsertCategories(ctx context.Context, entities []*Category) (err error) {
+	if len(entities) == 0 {
+		return nil
+	}
+	saved := make([]Category, len(entities))
+	for i, entity := range entities {
+		saved[i] = *entity
+	}
+	defer func() {
 		if err != nil {
+			for i, entity := range entities {
+				*entity = saved[i]
+			}
+		}
+	}()
+
+	rows := make([][]interface{}, len(entities))
+	for i, entity := range entities {
+		if hook, ok := interface{}(entity).(BeforeInserter); ok {
+			if err := hook.BeforeInsert(); err != nil {
+				return err
+			}
+		}
+		if err := entity.Validate(); err != nil {
 			return err
 		}
-		affected, err := result.RowsAffected()
+		rows[i] = []interface{}{entity.Name}
+	}
+	return transact(ctx, true, func(db executor) error {
+		head := "INSERT INTO `category` (`name`) VALUES "
+		return insertBatches(ctx, db, head, "(?)", rows, func(offset, count int, firstId int64) error {
+			if firstId != 0 {
+				for i := 0; i < count; i++ {
+					entities[offset+i].Id = firstId + int64(i)
+				}
+			}
+			for _, entity := range entities[offset : offset+count] {
+				if hook, ok := interface{}(entity).(AfterInserter); ok {
+					if err := hook.AfterInsert(); err != nil {
+						return err
+					}
+				}
+				entity.takeSnapshot()
+			}
+			return nil
+		})
+	})
+}
+
+// Delete Category row from the database. Returns sql.ErrNoRows
+// when the row is missing
+func (this *Category) Delete() error {
+	return persist(this, deleteOperation, func(db executor) error {
+		sql := "DELETE FROM `category` WHERE `id` = ?"
+		result, err := db.Exec(sql, this.Id)
 		if err != nil {
 			return err
-		} else if affected != 1 {
-			return fmt.Errorf("Wrong number of rows affected. Expected 1. Got %d", affected)
 		}
+		return affectedRow(result)
+	})
+}
+
+// find related Articles
+func (this *Category) FindArticles() ([]*Article, error) {
+	sql := "WHERE `article`.`category_id` = ?"
+	return FindArticles(sql, this.Id)
+}
+
+// related Articles. Loaded from the database on first use
+func (this *Category) Articles() ([]*Article, error) {
+	if !this.articlesLoaded {
+		return this.ReloadArticles()
+	}
+	return this.articles, nil
+}
+
+// load related Articles from the database again
+func (this *Category) ReloadArticles() ([]*Article, error) {
+	related, err := this.FindArticles()
+	if err != nil {
+		return nil, err
+	}
+	this.articles = related
+	this.articlesLoaded = true
+	return related, nil
+}
+
+// set related Articles and point their CategoryId to this Category
+func (this *Category) SetArticles(related []*Article) {
+	this.articles = related
+	this.articlesLoaded = true
+	for _, item := range related {
+		item.CategoryId = this.Id
+	}
+}
+
+// preload Articles of the Categories with a single query
+func LoadCategoriesArticles(entities []*Category) error {
+	// keys of the entities
+	var keys []interface{}
+	seen := make(map[interface{}]bool)
+	for _, entity := range entities {
+		if !seen[entity.Id] {
+			seen[entity.Id] = true
+			keys = append(keys, entity.Id)
+		}
+	}
+	related := make(map[interface{}][]*Article)
+	if len(keys) > 0 {
+		sql := "WHERE `article`.`category_id` IN (" + placeholders(len(keys)) + ")"
+		found, err := FindArticles(append([]interface{}{sql}, keys...)...)
+		if err != nil {
+			return err
+		}
+		for _, item := range found {
+			related[item.CategoryId] = append(related[item.CategoryId], item)
+		}
+	}
+	// attach to the entities
+	for _, entity := range entities {
+		entity.articles = related[entity.Id]
+		entity.articlesLoaded = true
+	}
+	return nil
+}
+
+// json representation of Category
+type categoryJSON struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// encode Category as json
+func (this Category) MarshalJSON() ([]byte, error) {
+	var data categoryJSON
+	data.Id = this.Id
+	data.Name = this.Name
+	return json.Marshal(data)
+}
+
+// decode Category from json. Fields missing from the json are
+// left unchanged
+func (this *Category) UnmarshalJSON(b []byte) error {
+	var data struct {
+		Id   jsonValue[int64]  `json:"id"`
+		Name jsonValue[string] `json:"name"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if data.Id.Value != nil {
+		this.Id = *data.Id.Value
+	}
+	if data.Name.Value != nil {
+		this.Name = *data.Name.Value
 	}
 	return nil
 }